@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/analyzer"
+	"github.com/YKarmar/JobTracker/internal/config"
+	"github.com/YKarmar/JobTracker/internal/exporter"
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// runAnalyze 实现 `jobtracker analyze`：分析邮件缓存中的邮件并写入申请库，不需要连接邮箱
+func runAnalyze(opts *options, args []string) {
+	fs := opts.flagSet("analyze", "")
+	all := fs.Bool("all", false, "重新分析邮件缓存中的所有邮件（如修改提示词或模型后），替换之前的分析结果")
+	noCache := fs.Bool("no-cache", false, "不使用LLM回复缓存，每封邮件都重新调用LLM")
+	fs.Parse(args)
+
+	cfg := loadConfig(opts)
+	if *noCache {
+		cfg.LLM.Cache.Disabled = true
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	// 显式指定 --start/--end 时只分析该时间范围内的邮件
+	var from, to time.Time
+	if opts.start != "" {
+		from = config.ParseDateLoose(opts.start, time.Time{})
+	}
+	if opts.end != "" {
+		// 与 fetch --end 一样包含结束日期当天，to 取次日零点（不包含）
+		to = config.ParseDateLoose(opts.end, time.Time{}).AddDate(0, 0, 1)
+	}
+
+	analyzePending(ctx, cfg, *all, from, to, os.Stdout)
+}
+
+// analyzePending 分析邮件缓存中的邮件并把结果记录到申请库。默认跳过已分析过的邮件，
+// all 为 true 时全部重新分析。分析失败的邮件不做记录，下次运行会重试。进度和统计写到 progress。
+// from/to 非零时只分析 [from, to) 范围内的邮件
+func analyzePending(ctx context.Context, cfg *config.Config, all bool, from, to time.Time, progress io.Writer) []types.JobApplication {
+	saved, err := openMailCache(cfg).List()
+	if err != nil {
+		log.Fatalf("读取邮件缓存失败: %v", err)
+	}
+
+	appStore, err := store.Open(cfg.Store.Path)
+	if err != nil {
+		log.Fatalf("打开申请库失败: %v", err)
+	}
+	defer appStore.Close()
+
+	var emails []types.Email
+	for _, email := range saved {
+		if (!from.IsZero() && email.Date.Before(from)) || (!to.IsZero() && !email.Date.Before(to)) {
+			continue
+		}
+		if !all {
+			analyzed, err := appStore.Analyzed(email)
+			if err != nil {
+				log.Fatalf("读取申请库失败: %v", err)
+			}
+			if analyzed {
+				continue
+			}
+		}
+		emails = append(emails, email)
+	}
+
+	if len(emails) == 0 {
+		fmt.Fprintf(progress, "邮件缓存中共 %d 封邮件，没有需要分析的邮件\n", len(saved))
+		return nil
+	}
+
+	jobAnalyzer := newAnalyzer(cfg, progress)
+	if jobAnalyzer.Cache != nil {
+		defer jobAnalyzer.Cache.Close()
+	}
+	fmt.Fprintf(progress, "\n正在使用LLM分析 %d 封邮件...\n", len(emails))
+	report, err := jobAnalyzer.AnalyzeEmails(ctx, emails)
+	if err != nil {
+		log.Printf("分析邮件中断: %v，已完成的结果会被保存", err)
+	}
+	printUsage(progress, jobAnalyzer.Stats())
+
+	recorded := 0
+	for _, result := range report.Results {
+		if result.Err != nil {
+			continue
+		}
+		if err := appStore.Record(result.Email, result.Application); err != nil {
+			log.Printf("保存分析结果失败 (%s): %v", result.Email.Subject, err)
+			continue
+		}
+		recorded++
+	}
+
+	if failed := report.Failed(); len(failed) > 0 {
+		fmt.Fprintf(progress, "\n⚠️  %d 封邮件分析失败（下次运行会重试）:\n", len(failed))
+		for _, result := range failed {
+			fmt.Fprintf(progress, "  • %s (%s): %v\n", result.Email.Subject, result.Email.Date.Format("01-02"), result.Err)
+		}
+	}
+	fmt.Fprintf(progress, "已记录 %d 封邮件的分析结果\n", recorded)
+
+	jobApplications := report.Applications()
+	exporter.PrintJobStatistics(progress, jobApplications)
+	return jobApplications
+}
+
+// printUsage 打印本次LLM调用的token用量和缓存命中情况
+func printUsage(progress io.Writer, stats analyzer.UsageStats) {
+	fmt.Fprintf(progress, "LLM请求 %d 次，消耗 %d token（输入 %d，输出 %d）",
+		stats.Requests, stats.Usage.Total(), stats.Usage.PromptTokens, stats.Usage.CompletionTokens)
+	if stats.CacheHits > 0 {
+		fmt.Fprintf(progress, "；%d 封邮件命中缓存，节省约 %d token", stats.CacheHits, stats.Saved.Total())
+	}
+	fmt.Fprintln(progress)
+}
+
+// newAnalyzer 按配置创建LLM分析器，并逐封打印进度。未关闭缓存时打开回复缓存，由调用方关闭
+func newAnalyzer(cfg *config.Config, progress io.Writer) *analyzer.JobAnalyzer {
+	llmConfig := analyzer.LLMConfig{
+		Provider:    cfg.LLM.Provider,
+		APIBase:     cfg.LLM.APIBase,
+		APIKey:      cfg.LLM.APIKey,
+		Model:       cfg.LLM.Model,
+		Temperature: cfg.LLM.Temperature,
+		MaxTokens:   cfg.LLM.MaxTokens,
+		BodyTokens:  cfg.LLM.BodyTokens,
+
+		Concurrency:       cfg.LLM.Concurrency,
+		RequestsPerMinute: cfg.LLM.RequestsPerMinute,
+		TokensPerMinute:   cfg.LLM.TokensPerMinute,
+		ResponseFormat:    cfg.LLM.ResponseFormat,
+		MinConfidence:     cfg.LLM.MinConfidence,
+	}
+
+	if llmConfig.MaxTokens == 0 {
+		llmConfig.MaxTokens = 2000
+	}
+
+	jobAnalyzer, err := analyzer.NewJobAnalyzer(llmConfig)
+	if err != nil {
+		log.Fatalf("创建LLM分析器失败: %v", err)
+	}
+	if !cfg.LLM.Cache.Disabled {
+		ttl := time.Duration(cfg.LLM.Cache.TTLDays) * 24 * time.Hour
+		if jobAnalyzer.Cache, err = analyzer.OpenResponseCache(cfg.LLM.Cache.Path, ttl); err != nil {
+			log.Fatalf("打开LLM缓存失败: %v", err)
+		}
+	}
+	jobAnalyzer.OnResult = func(done, total int, result analyzer.EmailResult) {
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(progress, "[%d/%d] ❌ %s: %v\n", done, total, result.Email.Subject, result.Err)
+		case result.Application != nil:
+			app := result.Application
+			fmt.Fprintf(progress, "[%d/%d] 发现求职邮件: %s - %s (%s)%s\n", done, total, app.Company, app.Position, app.Status, cachedMark(result))
+		default:
+			fmt.Fprintf(progress, "[%d/%d] 跳过: %s%s\n", done, total, result.Email.Subject, cachedMark(result))
+		}
+	}
+	return jobAnalyzer
+}
+
+func cachedMark(result analyzer.EmailResult) string {
+	if result.Cached {
+		return "（缓存）"
+	}
+	return ""
+}
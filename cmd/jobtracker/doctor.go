@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/config"
+	"github.com/YKarmar/JobTracker/internal/exporter"
+	"github.com/YKarmar/JobTracker/internal/mailcache"
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// runDoctor 实现 `jobtracker doctor`：逐项检查运行环境，有失败项时以状态码 1 退出
+func runDoctor(opts *options, args []string) {
+	fs := opts.flagSet("doctor", "")
+	fs.Parse(args)
+
+	failed := 0
+	check := func(name string, err error, detail string) {
+		if err != nil {
+			failed++
+			fmt.Printf("❌ %s: %v\n", name, err)
+			return
+		}
+		fmt.Printf("✅ %s: %s\n", name, detail)
+	}
+
+	path, err := config.Locate(opts.config)
+	if err != nil {
+		check("配置文件", err, "")
+		os.Exit(1)
+	}
+	cfg, err := config.Load(path)
+	check("配置文件", err, path)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	check("数据目录", checkWritable(cfg.DataDir), cfg.DataDir)
+
+	appStore, err := store.Open(cfg.Store.Path)
+	if err == nil {
+		var apps []store.Application
+		apps, err = appStore.List()
+		appStore.Close()
+		check("申请库", err, fmt.Sprintf("%s（%d 个申请）", cfg.Store.Path, len(apps)))
+	} else {
+		check("申请库", err, "")
+	}
+
+	cache, err := mailcache.Open(cfg.MailCache.Dir)
+	if err == nil {
+		var saved []types.Email
+		saved, err = cache.List()
+		check("邮件缓存", err, fmt.Sprintf("%s（%d 封）", cfg.MailCache.Dir, len(saved)))
+	} else {
+		check("邮件缓存", err, "")
+	}
+
+	if cfg.MCP.Command != "" {
+		bin, err := exec.LookPath(cfg.MCP.Command)
+		check("MCP服务器", err, bin+"（stdio）")
+	} else {
+		endpoint := cfg.MCP.Endpoint
+		if endpoint == "" {
+			endpoint = "http://localhost:8080/mcp"
+		}
+		check("MCP服务器", checkReachable(endpoint), endpoint)
+	}
+
+	var llmErr error
+	switch {
+	case cfg.LLM.Model == "":
+		llmErr = fmt.Errorf("llm.model 未设置")
+	case cfg.LLM.APIKey == "" && cfg.LLM.Provider != "ollama":
+		llmErr = fmt.Errorf("未设置 %s 的API密钥（llm.api_key 或 %s）", cfg.LLM.Provider, providerKeyEnv(cfg.LLM.Provider))
+	}
+	check("LLM配置", llmErr, fmt.Sprintf("%s / %s", cfg.LLM.Provider, cfg.LLM.Model))
+	if cfg.LLM.APIBase != "" {
+		check("LLM服务", checkReachable(cfg.LLM.APIBase), cfg.LLM.APIBase)
+	}
+
+	_, err = exporter.New(cfg.Export.Format)
+	check("导出格式", err, fmt.Sprintf("%s -> %s", cfg.Export.Format, cfg.Export.File))
+
+	if failed > 0 {
+		fmt.Printf("\n%d 项检查失败\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("\n所有检查均已通过")
+}
+
+func providerKeyEnv(provider string) string {
+	if provider == "anthropic" {
+		return "ANTHROPIC_API_KEY"
+	}
+	return "OPENAI_API_KEY"
+}
+
+// checkWritable 确认目录存在（必要时创建）且可写
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkReachable 确认能连接到地址对应的主机端口，不发送请求
+func checkReachable(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("地址无效: %q", rawURL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	conn, err := net.DialTimeout("tcp", host, 3*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
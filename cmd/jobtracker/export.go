@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/YKarmar/JobTracker/internal/config"
+	"github.com/YKarmar/JobTracker/internal/exporter"
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// exportTarget 导出的格式和文件
+type exportTarget struct {
+	exporter exporter.Exporter
+	file     string // "-" 表示标准输出
+	merge    bool
+	isCSV    bool
+}
+
+// newExportTarget 根据配置和命令行参数确定导出目标。未显式指定 --out 时，
+// 默认的 .csv 文件名换成所选格式的扩展名
+func newExportTarget(cfg *config.Config, opts *options, format string, merge bool) exportTarget {
+	if format != "" {
+		cfg.Export.Format = format
+	}
+	appExporter, err := exporter.New(cfg.Export.Format)
+	if err != nil {
+		log.Fatalf("导出格式无效: %v", err)
+	}
+
+	file := cfg.Export.File
+	if opts.out == "" {
+		file = withExtension(file, appExporter.Extension())
+	}
+	if merge {
+		cfg.Export.Merge = true
+	}
+
+	_, isCSV := appExporter.(*exporter.CSVExporter)
+	if cfg.Export.Merge && (!isCSV || file == "-") {
+		log.Fatalf("合并模式只支持导出到CSV文件")
+	}
+	return exportTarget{exporter: appExporter, file: file, merge: cfg.Export.Merge, isCSV: isCSV}
+}
+
+// progress 进度信息的输出位置：导出到标准输出时改写到标准错误，避免混进导出结果
+func (t exportTarget) progress() io.Writer {
+	if t.file == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// runExport 实现 `jobtracker export`：把申请库导出为文件，不获取或分析邮件
+func runExport(opts *options, args []string) {
+	fs := opts.flagSet("export", "")
+	format := fs.String("format", "", "导出格式（默认使用 export.format）")
+	merge := fs.Bool("merge", false, "合并到已有的CSV文件而不是覆盖（也可设置 export.merge）")
+	fs.Parse(args)
+
+	cfg := loadConfig(opts)
+	target := newExportTarget(cfg, opts, *format, *merge)
+	runSummary(cfg, target, nil, target.progress())
+}
+
+// runSummary 读取申请库，提示异常的状态变化并导出结果，进度写到 progress。jobApplications 为本次分析得到的求职邮件
+func runSummary(cfg *config.Config, target exportTarget, jobApplications []types.JobApplication, progress io.Writer) {
+	appStore, err := store.Open(cfg.Store.Path)
+	if err != nil {
+		log.Fatalf("打开申请库失败: %v", err)
+	}
+	applications, err := appStore.List()
+	appStore.Close()
+	if err != nil {
+		log.Fatalf("读取申请库失败: %v", err)
+	}
+	fmt.Fprintf(progress, "\n申请库中共 %d 个申请（本次分析发现 %d 封求职邮件）\n", len(applications), len(jobApplications))
+
+	for _, app := range applications {
+		for _, t := range app.Flagged() {
+			fmt.Fprintf(progress, "⚠️  %s - %s: %s（%s，%s）\n", app.Company, app.Position, t.Note, t.Subject, t.At.Format("01-02"))
+		}
+	}
+
+	if len(applications) == 0 {
+		fmt.Fprintln(progress, "申请库为空，没有需要导出的内容")
+		return
+	}
+
+	exportApplications(cfg, target, applications, progress)
+
+	fmt.Fprintln(progress, "\n=== 分析完成 ===")
+
+	// 显示最近的求职活动
+	fmt.Fprintln(progress, "\n最近的求职活动:")
+	for i, app := range applications {
+		if i >= 5 { // 只显示最近5条
+			break
+		}
+		fmt.Fprintf(progress, "• %s - %s (%s) [%s]\n",
+			app.Company, app.Position, app.Status, app.Latest().Date.Format("01-02"))
+	}
+	if len(applications) > 5 {
+		fmt.Fprintf(progress, "... 还有 %d 条记录，可使用 jobtracker list 查看\n", len(applications)-5)
+	}
+}
+
+// exportApplications 按导出目标写出申请，CSV格式额外导出状态时间线和统计信息
+func exportApplications(cfg *config.Config, target exportTarget, applications []store.Application, progress io.Writer) {
+	switch {
+	case target.file == "-":
+		if err := target.exporter.Write(os.Stdout, applications); err != nil {
+			log.Printf("导出失败: %v", err)
+		}
+	case target.merge:
+		fmt.Fprintf(progress, "\n正在合并结果到 %s...\n", target.file)
+		result, err := exporter.NewCSVExporter(target.file).MergeApplications(applications)
+		if err != nil {
+			log.Printf("合并导出失败: %v", err)
+		} else {
+			fmt.Fprintf(progress, "✅ 已合并到: %s（更新 %d 行，新增 %d 行，保留 %d 处手动修改）\n",
+				target.file, result.Updated, result.Added, result.Preserved)
+		}
+	default:
+		fmt.Fprintf(progress, "\n正在导出结果到 %s...\n", target.file)
+		if err := exporter.Export(target.exporter, target.file, applications); err != nil {
+			log.Printf("导出失败: %v", err)
+		} else {
+			fmt.Fprintf(progress, "✅ 求职信息已导出到: %s\n", target.file)
+		}
+	}
+
+	if target.isCSV && target.file != "-" {
+		csvExporter := exporter.NewCSVExporter(target.file)
+		if timelineFile, err := csvExporter.ExportTimeline(applications); err != nil {
+			log.Printf("导出状态时间线失败: %v", err)
+		} else {
+			fmt.Fprintf(progress, "✅ 状态时间线已导出到: %s\n", timelineFile)
+		}
+
+		if statsFile, err := csvExporter.ExportStatistics(exporter.EmailRecords(applications)); err != nil {
+			log.Printf("导出统计信息失败: %v", err)
+		} else {
+			fmt.Fprintf(progress, "✅ 统计信息已导出到: %s\n", statsFile)
+		}
+	}
+
+	// 导出面试日历
+	if cfg.Export.Calendar != "" {
+		if err := exporter.NewICSExporter(cfg.Export.Calendar).ExportEvents(applications); err != nil {
+			log.Printf("导出面试日历失败: %v", err)
+		} else {
+			fmt.Fprintf(progress, "✅ 面试日历已导出到: %s\n", cfg.Export.Calendar)
+		}
+	}
+}
+
+// runStats 实现 `jobtracker stats`：按申请库中的全部邮件统计
+func runStats(opts *options, args []string) {
+	fs := opts.flagSet("stats", "")
+	fs.Parse(args)
+
+	cfg := loadConfig(opts)
+	applications := listApplications(cfg)
+
+	exporter.PrintJobStatistics(os.Stdout, exporter.EmailRecords(applications))
+
+	counts := make(map[types.Status]int)
+	var order []types.Status
+	for _, app := range applications {
+		if counts[app.Status] == 0 {
+			order = append(order, app.Status)
+		}
+		counts[app.Status]++
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].Stage() != order[j].Stage() {
+			return order[i].Stage() < order[j].Stage()
+		}
+		return order[i] < order[j]
+	})
+	fmt.Printf("\n申请当前状态（共 %d 个申请）:\n", len(applications))
+	for _, status := range order {
+		fmt.Printf("  %s: %d\n", status, counts[status])
+	}
+}
+
+// listApplications 读取申请库中的全部申请
+func listApplications(cfg *config.Config) []store.Application {
+	appStore, err := store.Open(cfg.Store.Path)
+	if err != nil {
+		log.Fatalf("打开申请库失败: %v", err)
+	}
+	defer appStore.Close()
+
+	applications, err := appStore.List()
+	if err != nil {
+		log.Fatalf("读取申请库失败: %v", err)
+	}
+	return applications
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/client"
+	"github.com/YKarmar/JobTracker/internal/config"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// interruptContext Ctrl+C 取消整个流程，进行中的MCP请求会通知服务器停止
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// newEmailClient 按配置创建MCP邮件客户端
+func newEmailClient(cfg *config.Config) *client.MCPEmailClient {
+	mcpConfig := client.MCPEmailConfig{
+		Provider:    client.ParseEmailProvider(cfg.IMAP.Provider),
+		Email:       cfg.IMAP.Email,
+		MCPEndpoint: "http://localhost:8080/mcp", // 默认MCP端点
+	}
+
+	// 如果有MCP配置，使用配置的值
+	if cfg.MCP.Endpoint != "" {
+		mcpConfig.MCPEndpoint = cfg.MCP.Endpoint
+	}
+	if cfg.MCP.APIKey != "" {
+		mcpConfig.APIKey = cfg.MCP.APIKey
+	}
+	if cfg.MCP.Command != "" {
+		mcpConfig.Command = cfg.MCP.Command
+		mcpConfig.Args = cfg.MCP.Args
+	}
+
+	return client.NewMCPEmailClient(mcpConfig)
+}
+
+// login 触发邮箱登录，需要浏览器授权时等待用户完成，提示信息写到 progress
+func login(ctx context.Context, cfg *config.Config, emailClient *client.MCPEmailClient, progress io.Writer) {
+	fmt.Fprintf(progress, "正在为邮箱 %s 启动登录流程...\n", cfg.IMAP.Email)
+	fmt.Fprintf(progress, "检测到邮箱提供商: %s\n", cfg.IMAP.Provider)
+
+	session, err := emailClient.InitiateEmailLogin(ctx)
+	if err != nil {
+		log.Fatalf("启动邮箱登录失败: %v\n提示: 如需测试，可使用 --mock 参数", err)
+	}
+
+	if session.LoginURL != "" {
+		fmt.Fprintf(progress, "请在浏览器中完成登录: %s\n", session.LoginURL)
+
+		// 自动打开浏览器
+		if err := openBrowser(progress, session.LoginURL); err != nil {
+			fmt.Fprintf(progress, "无法自动打开浏览器，请手动访问上述链接\n")
+		}
+
+		fmt.Fprintln(progress, "等待登录完成...")
+		// 这里应该实现等待登录完成的逻辑
+		time.Sleep(30 * time.Second) // 简单等待，实际应该轮询状态
+	}
+}
+
+// runLogin 实现 `jobtracker login`：只启动登录流程，不获取邮件
+func runLogin(opts *options, args []string) {
+	fs := opts.flagSet("login", "")
+	fs.Parse(args)
+
+	cfg := loadConfig(opts)
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	emailClient := newEmailClient(cfg)
+	defer emailClient.Close()
+
+	login(ctx, cfg, emailClient, os.Stdout)
+	fmt.Println("✅ 登录流程已完成")
+}
+
+// runFetch 实现 `jobtracker fetch`：获取邮件并保存到邮件缓存，之后可用 analyze 反复分析
+func runFetch(opts *options, args []string) {
+	fs := opts.flagSet("fetch", "")
+	mockMode := fs.Bool("mock", false, "使用模拟数据（用于测试，不保存到数据目录）")
+	sinceLastRun := fs.Bool("since-last-run", false, "只获取上次运行之后的新邮件（增量同步）")
+	fs.Parse(args)
+
+	cfg := loadConfig(opts)
+	if *mockMode {
+		defer useMockDataDir(cfg, os.Stdout)()
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	fetchAndSave(ctx, cfg, *mockMode, *sinceLastRun, os.Stdout)
+}
+
+// fetchAndSave 获取邮件并保存到邮件缓存，返回获取到的邮件数，进度写到 progress。
+// 邮件保存成功后才更新增量同步游标，中途失败时下次运行会重新获取同一批邮件
+func fetchAndSave(ctx context.Context, cfg *config.Config, mockMode, sinceLastRun bool, progress io.Writer) int {
+	var emails []types.Email
+	var nextCursor string
+
+	if mockMode {
+		fmt.Fprintln(progress, "🧪 使用模拟模式进行测试...")
+		emails = generateMockEmails()
+	} else {
+		emailClient := newEmailClient(cfg)
+		defer emailClient.Close()
+
+		login(ctx, cfg, emailClient, progress)
+
+		start, end := fetchRange(cfg)
+		query := client.EmailQuery{
+			StartDate: start,
+			EndDate:   end,
+			MaxEmails: cfg.Fetch.MaxEmails,
+			Folders:   cfg.IMAP.Folders,
+			Keywords:  cfg.Fetch.Keywords,
+
+			SenderDomains:   cfg.Fetch.SenderDomains,
+			SubjectPatterns: cfg.Fetch.SubjectPatterns,
+			OnProgress: func(p client.Progress) {
+				fmt.Fprintf(progress, "  ⏳ %s\n", p.Message)
+			},
+		}
+
+		if sinceLastRun {
+			cursor, err := loadSyncCursor(cfg.DataDir, cfg.IMAP.Email)
+			if err != nil {
+				log.Fatalf("读取同步游标失败: %v", err)
+			}
+
+			if cursor == "" {
+				fmt.Fprintf(progress, "首次增量同步，从 %s 开始...\n", start.Format("2006-01-02"))
+			} else {
+				fmt.Fprintln(progress, "正在增量同步上次运行之后的新邮件...")
+			}
+
+			result, err := emailClient.SyncEmails(ctx, query, cursor)
+			if err != nil {
+				log.Fatalf("同步邮件失败: %v", err)
+			}
+
+			emails = result.Emails
+			nextCursor = result.Cursor
+			for _, folder := range result.Resynced {
+				fmt.Fprintf(progress, "文件夹 %s 已全量同步\n", folder)
+			}
+			if result.HasMore {
+				fmt.Fprintf(progress, "新邮件超过 %d 封，剩余部分将在下次运行时获取\n", query.MaxEmails)
+			}
+		} else {
+			fmt.Fprintf(progress, "正在获取邮件 (时间范围: %s 到 %s)...\n",
+				start.Format("2006-01-02"), end.Format("2006-01-02"))
+
+			var err error
+			emails, err = emailClient.FetchEmails(ctx, query)
+			if err != nil {
+				log.Fatalf("获取邮件失败: %v", err)
+			}
+		}
+	}
+
+	fmt.Fprintf(progress, "成功获取 %d 封邮件\n", len(emails))
+
+	added, err := saveToMailCache(cfg, emails, progress)
+	if err != nil {
+		log.Fatalf("保存邮件失败: %v", err)
+	}
+	if len(emails) > 0 {
+		fmt.Fprintf(progress, "已保存到邮件缓存（新增 %d 封）\n", added)
+	}
+
+	commitSyncCursor(cfg.DataDir, cfg.IMAP.Email, nextCursor)
+	return len(emails)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/config"
+	"github.com/YKarmar/JobTracker/internal/exporter"
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// runFollowups 实现 `jobtracker followups`：列出长时间没有新邮件的申请，可导出为iCalendar提醒
+func runFollowups(opts *options, args []string) {
+	fs := opts.flagSet("followups", "")
+	icsFile := fs.String("ics", "", "把跟进提醒导出为iCalendar文件")
+	asOf := fs.String("as-of", "", "按指定日期（YYYY-MM-DD）计算，默认今天")
+	fs.Parse(args)
+
+	cfg := loadConfig(opts)
+
+	now := config.ParseDateLoose(*asOf, time.Now())
+
+	appStore, err := store.Open(cfg.Store.Path)
+	if err != nil {
+		log.Fatalf("打开申请库失败: %v", err)
+	}
+	defer appStore.Close()
+
+	applications, err := appStore.List()
+	if err != nil {
+		log.Fatalf("读取申请库失败: %v", err)
+	}
+
+	followUps := store.FindFollowUps(applications, followUpRules(cfg), now)
+	if len(followUps) == 0 {
+		fmt.Printf("申请库中共 %d 个申请，暂无需要跟进的申请\n", len(applications))
+		return
+	}
+
+	fmt.Printf("=== 需要跟进的申请（%d/%d）===\n", len(followUps), len(applications))
+	for _, f := range followUps {
+		app := f.Application
+		fmt.Printf("• [%s] %s - %s (%s) 已 %d 天没有新邮件，建议 %s 跟进\n",
+			f.State, app.Company, app.Position, app.Status, f.IdleDays, f.SuggestedDate.Format("2006-01-02"))
+	}
+
+	if *icsFile != "" {
+		if err := exporter.NewICSExporter(*icsFile).ExportFollowUps(followUps); err != nil {
+			log.Fatalf("导出跟进提醒失败: %v", err)
+		}
+		fmt.Printf("✅ 跟进提醒已导出到: %s\n", *icsFile)
+	}
+}
+
+// followUpRules 用配置覆盖默认的各阶段等待天数
+func followUpRules(cfg *config.Config) store.FollowUpRules {
+	rules := store.DefaultFollowUpRules()
+	for status, days := range cfg.Followups.StaleDays {
+		rules.StaleDays[types.Status(strings.ToUpper(status))] = days
+	}
+	for status, days := range cfg.Followups.GhostedDays {
+		rules.GhostedDays[types.Status(strings.ToUpper(status))] = days
+	}
+	return rules
+}
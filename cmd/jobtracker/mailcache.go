@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/config"
+	"github.com/YKarmar/JobTracker/internal/mailcache"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// openMailCache 打开原始邮件缓存
+func openMailCache(cfg *config.Config) *mailcache.Cache {
+	cache, err := mailcache.Open(cfg.MailCache.Dir)
+	if err != nil {
+		log.Fatalf("打开邮件缓存失败: %v", err)
+	}
+	return cache
+}
+
+// saveToMailCache 把获取到的邮件写入缓存并按保留策略清理，返回新增的数量
+func saveToMailCache(cfg *config.Config, emails []types.Email, progress io.Writer) (int, error) {
+	cache := openMailCache(cfg)
+	fetchedAt := time.Now()
+
+	added := 0
+	for _, email := range emails {
+		ok, err := cache.Put(email)
+		if err != nil {
+			return added, err
+		}
+		if ok {
+			added++
+		}
+	}
+
+	retention := mailcache.Retention{
+		MaxAge:      time.Duration(cfg.MailCache.RetentionDays) * 24 * time.Hour,
+		MaxMessages: cfg.MailCache.MaxMessages,
+		// 本次获取的邮件不清理，超过 max_messages 时只删除之前获取的
+		KeepFetchedSince: fetchedAt,
+	}
+	evicted, err := cache.Prune(retention, time.Now())
+	if err != nil {
+		return added, err
+	}
+	if evicted > 0 {
+		fmt.Fprintf(progress, "按保留策略清理了 %d 封缓存的邮件\n", evicted)
+	}
+	return added, nil
+}
@@ -1,173 +1,227 @@
 package main
 
 import (
-	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/YKarmar/JobTracker/internal/analyzer"
-	"github.com/YKarmar/JobTracker/internal/client"
 	"github.com/YKarmar/JobTracker/internal/config"
-	"github.com/YKarmar/JobTracker/internal/exporter"
 	"github.com/YKarmar/JobTracker/internal/types"
 )
 
-func main() {
-	// 命令行参数
-	mockMode := flag.Bool("mock", false, "使用模拟数据（用于测试）")
-	flag.Parse()
-
-	fmt.Println("=== JobTracker 求职邮件分析工具 ===")
-	fmt.Println("正在加载配置...")
+// command 一个子命令
+type command struct {
+	name    string
+	summary string
+	run     func(opts *options, args []string)
+}
 
-	// 1. 加载配置
-	cfg, err := config.Load("configs/config.yaml")
-	if err != nil {
-		log.Fatalf("加载配置失败: %v", err)
+var commands []command
+
+func init() {
+	// 在 init 中赋值，避免 usage 引用 commands 造成初始化循环
+	commands = []command{
+		{"run", "获取新邮件、分析并导出（默认命令）", runPipeline},
+		{"login", "启动邮箱登录流程", runLogin},
+		{"fetch", "获取邮件并保存到邮件缓存", runFetch},
+		{"analyze", "分析邮件缓存中的邮件并写入申请库", runAnalyze},
+		{"export", "把申请库导出为文件", runExport},
+		{"list", "列出申请库中的申请", runList},
+		{"show", "查看一个申请的邮件和状态时间线", runShow},
+		{"stats", "显示申请库的统计信息", runStats},
+		{"followups", "列出需要跟进的申请", runFollowups},
+		{"doctor", "检查配置、数据目录、MCP服务器和LLM服务", runDoctor},
 	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
-	defer cancel()
-
-	var emails []types.Email
-
-	if *mockMode {
-		fmt.Println("🧪 使用模拟模式进行测试...")
-		emails = generateMockEmails()
-	} else {
-		// 2. 设置MCP邮件客户端
-		mcpConfig := client.MCPEmailConfig{
-			Provider:    client.ParseEmailProvider(cfg.IMAP.Provider),
-			Email:       cfg.IMAP.Email,
-			MCPEndpoint: "http://localhost:8080/mcp", // 默认MCP端点
-		}
-
-		// 如果有MCP配置，使用配置的值
-		if cfg.MCP.Endpoint != "" {
-			mcpConfig.MCPEndpoint = cfg.MCP.Endpoint
-		}
-		if cfg.MCP.APIKey != "" {
-			mcpConfig.APIKey = cfg.MCP.APIKey
-		}
+// options 所有子命令共用的全局参数，可放在子命令前或后
+type options struct {
+	config    string
+	start     string
+	end       string
+	maxEmails int
+	out       string
+}
 
-		emailClient := client.NewMCPEmailClient(mcpConfig)
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.config, "config", o.config, "配置文件（默认依次查找 $JOBTRACKER_CONFIG、./configs/config.yaml、~/.jobtracker/config.yaml）")
+	fs.StringVar(&o.start, "start", o.start, "开始日期 YYYY-MM-DD，覆盖 fetch.start")
+	fs.StringVar(&o.end, "end", o.end, "结束日期 YYYY-MM-DD，覆盖 fetch.end")
+	fs.IntVar(&o.maxEmails, "max-emails", o.maxEmails, "最多获取的邮件数，覆盖 fetch.max_emails")
+	fs.StringVar(&o.out, "out", o.out, "导出文件，- 表示标准输出，覆盖 export.file")
+}
 
-		// 3. 触发邮箱登录
-		fmt.Printf("正在为邮箱 %s 启动登录流程...\n", cfg.IMAP.Email)
-		fmt.Printf("检测到邮箱提供商: %s\n", cfg.IMAP.Provider)
+// flagSet 创建子命令的参数集，包含全局参数
+func (o *options) flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	o.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: jobtracker %s [参数] %s\n\n参数:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
 
-		session, err := emailClient.InitiateEmailLogin(ctx)
-		if err != nil {
-			log.Fatalf("启动邮箱登录失败: %v\n提示: 如需测试，可使用 --mock 参数", err)
-		}
+func main() {
+	opts := &options{}
 
-		if session.LoginURL != "" {
-			fmt.Printf("请在浏览器中完成登录: %s\n", session.LoginURL)
+	// 先解析子命令前的全局参数；无法解析时（如旧用法 jobtracker --mock）整体交给默认命令
+	top := flag.NewFlagSet("jobtracker", flag.ContinueOnError)
+	top.SetOutput(io.Discard)
+	opts.register(top)
 
-			// 自动打开浏览器
-			if err := openBrowser(session.LoginURL); err != nil {
-				fmt.Printf("无法自动打开浏览器，请手动访问上述链接\n")
-			}
+	name, args := "run", os.Args[1:]
+	switch err := top.Parse(os.Args[1:]); {
+	case errors.Is(err, flag.ErrHelp):
+		usage()
+		return
+	case err != nil:
+		*opts = options{}
+	case top.NArg() > 0:
+		name, args = top.Arg(0), top.Args()[1:]
+	default:
+		args = nil
+	}
 
-			fmt.Println("等待登录完成...")
-			// 这里应该实现等待登录完成的逻辑
-			time.Sleep(30 * time.Second) // 简单等待，实际应该轮询状态
+	if name == "help" {
+		usage()
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cmd.run(opts, args)
+			return
 		}
+	}
 
-		// 4. 获取邮件
-		start := config.ParseDateLoose(cfg.Fetch.Start, time.Now().AddDate(0, 0, -7))
-		end := config.ParseDateLoose(cfg.Fetch.End, time.Now())
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", name)
+	usage()
+	os.Exit(2)
+}
 
-		query := client.EmailQuery{
-			StartDate: start,
-			EndDate:   end,
-			MaxEmails: cfg.Fetch.MaxEmails,
-			Folders:   cfg.IMAP.Folders,
-			Keywords:  []string{"job", "interview", "offer", "application", "招聘", "面试", "职位", "工作"},
-		}
+func usage() {
+	out := os.Stderr
+	fmt.Fprintln(out, "JobTracker 求职邮件分析工具")
+	fmt.Fprintln(out, "\n用法: jobtracker [全局参数] <命令> [参数]")
+	fmt.Fprintln(out, "\n命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out, "\n全局参数:")
+	fs := flag.NewFlagSet("jobtracker", flag.ContinueOnError)
+	fs.SetOutput(out)
+	(&options{}).register(fs)
+	fs.PrintDefaults()
+	fmt.Fprintln(out, "\n使用 jobtracker <命令> -h 查看命令的参数")
+}
 
-		fmt.Printf("正在获取邮件 (时间范围: %s 到 %s)...\n",
-			start.Format("2006-01-02"), end.Format("2006-01-02"))
+// loadConfig 加载配置并应用命令行覆盖
+func loadConfig(opts *options) *config.Config {
+	path, err := config.Locate(opts.config)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
 
-		emails, err = emailClient.FetchEmails(ctx, query)
-		if err != nil {
-			log.Fatalf("获取邮件失败: %v", err)
+	for _, d := range []struct{ flag, value string }{{"--start", opts.start}, {"--end", opts.end}} {
+		if d.value != "" && config.ParseDateLoose(d.value, time.Time{}).IsZero() {
+			log.Fatalf("%s 日期格式无效: %q（应为 YYYY-MM-DD 或 RFC3339）", d.flag, d.value)
 		}
 	}
-
-	fmt.Printf("成功获取 %d 封邮件\n", len(emails))
-
-	if len(emails) == 0 {
-		fmt.Println("没有找到邮件，程序结束")
-		return
+	if opts.start != "" {
+		cfg.Fetch.Start = opts.start
 	}
-
-	// 5. 使用LLM分析邮件
-	llmConfig := analyzer.LLMConfig{
-		APIBase:     cfg.LLM.APIBase,
-		APIKey:      cfg.LLM.APIKey,
-		Model:       cfg.LLM.Model,
-		Temperature: cfg.LLM.Temperature,
-		MaxTokens:   cfg.LLM.MaxTokens,
+	if opts.end != "" {
+		cfg.Fetch.End = opts.end
 	}
-
-	if llmConfig.MaxTokens == 0 {
-		llmConfig.MaxTokens = 2000
+	if opts.maxEmails > 0 {
+		cfg.Fetch.MaxEmails = opts.maxEmails
 	}
-
-	jobAnalyzer := analyzer.NewJobAnalyzer(llmConfig)
-
-	fmt.Println("\n正在使用LLM分析邮件内容...")
-	jobApplications, err := jobAnalyzer.AnalyzeEmails(ctx, emails)
-	if err != nil {
-		log.Fatalf("分析邮件失败: %v", err)
+	if opts.out != "" {
+		cfg.Export.File = opts.out
 	}
+	return cfg
+}
 
-	// 6. 显示统计信息
-	exporter.PrintJobStatistics(jobApplications)
+// fetchRange 获取邮件的时间范围，默认最近7天
+func fetchRange(cfg *config.Config) (time.Time, time.Time) {
+	start := config.ParseDateLoose(cfg.Fetch.Start, time.Now().AddDate(0, 0, -7))
+	end := config.ParseDateLoose(cfg.Fetch.End, time.Now())
+	return start, end
+}
 
-	// 7. 导出CSV文件
-	if len(jobApplications) > 0 {
-		exportFile := cfg.Export.File
-		if exportFile == "" {
-			exportFile = "job_applications_" + time.Now().Format("20060102_150405") + ".csv"
-		}
+// runPipeline 实现 `jobtracker run`（默认命令）：获取新邮件、分析尚未分析过的邮件并导出
+func runPipeline(opts *options, args []string) {
+	fs := opts.flagSet("run", "")
+	mockMode := fs.Bool("mock", false, "使用模拟数据（用于测试，使用临时数据目录，不影响申请库）")
+	sinceLastRun := fs.Bool("since-last-run", false, "只获取上次运行之后的新邮件（增量同步）")
+	format := fs.String("format", "", "导出格式（默认使用 export.format）")
+	merge := fs.Bool("merge", false, "合并到已有的CSV文件而不是覆盖（也可设置 export.merge）")
+	noCache := fs.Bool("no-cache", false, "不使用LLM回复缓存，每封邮件都重新调用LLM")
+	fs.StringVar(&opts.out, "output", opts.out, "同 --out（旧参数）")
+	fs.Parse(args)
+
+	cfg := loadConfig(opts)
+	if *noCache {
+		cfg.LLM.Cache.Disabled = true
+	}
+	target := newExportTarget(cfg, opts, *format, *merge)
+	// 按最终的导出文件（--out 或 export.file）决定进度信息的输出位置
+	progress := target.progress()
+	fmt.Fprintln(progress, "=== JobTracker 求职邮件分析工具 ===")
 
-		csvExporter := exporter.NewCSVExporter(exportFile)
+	if *mockMode {
+		defer useMockDataDir(cfg, progress)()
+	}
 
-		fmt.Printf("\n正在导出结果到 %s...\n", exportFile)
-		if err := csvExporter.ExportJobApplications(jobApplications); err != nil {
-			log.Printf("导出CSV失败: %v", err)
-		} else {
-			fmt.Printf("✅ 求职信息已导出到: %s\n", exportFile)
-		}
+	ctx, cancel := interruptContext()
+	defer cancel()
 
-		// 导出统计信息
-		if err := csvExporter.ExportStatistics(jobApplications); err != nil {
-			log.Printf("导出统计信息失败: %v", err)
-		}
+	fetched := fetchAndSave(ctx, cfg, *mockMode, *sinceLastRun, progress)
+	if fetched == 0 {
+		fmt.Fprintln(progress, "没有获取到新邮件")
 	}
 
-	fmt.Println("\n=== 分析完成 ===")
+	jobApplications := analyzePending(ctx, cfg, false, time.Time{}, time.Time{}, progress)
+	runSummary(cfg, target, jobApplications, progress)
+}
 
-	// 显示详细结果
-	if len(jobApplications) > 0 {
-		fmt.Println("\n最近的求职活动:")
-		count := 0
-		for _, app := range jobApplications {
-			if count >= 5 { // 只显示最近5条
-				break
-			}
-			fmt.Printf("• %s - %s (%s) [%s]\n",
-				app.Company, app.Position, app.Status, app.Email.Date.Format("01-02"))
-			count++
+// useMockDataDir 模拟模式改用临时数据目录，模拟邮件和分析结果不会混入真实的邮件缓存和申请库。
+// 返回的函数删除临时目录
+func useMockDataDir(cfg *config.Config, progress io.Writer) func() {
+	dir, err := os.MkdirTemp("", "jobtracker-mock-")
+	if err != nil {
+		log.Fatalf("创建临时数据目录失败: %v", err)
+	}
+	cfg.DataDir = dir
+	cfg.Store.Path = filepath.Join(dir, "applications.db")
+	cfg.MailCache.Dir = filepath.Join(dir, "mail")
+	cfg.LLM.Cache.Path = filepath.Join(dir, "llm_cache.db")
+	fmt.Fprintf(progress, "🧪 模拟模式使用临时数据目录 %s，结束后删除\n", dir)
+
+	return func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("删除临时数据目录失败: %v", err)
 		}
+	}
+}
 
-		if len(jobApplications) > 5 {
-			fmt.Printf("... 还有 %d 条记录，详情请查看CSV文件\n", len(jobApplications)-5)
-		}
+// commitSyncCursor 保存增量同步游标，非增量模式下 cursor 为空，不做任何事
+func commitSyncCursor(dataDir, email, cursor string) {
+	if cursor == "" {
+		return
+	}
+	if err := saveSyncCursor(dataDir, email, cursor); err != nil {
+		log.Printf("保存同步游标失败: %v", err)
 	}
 }
 
@@ -175,38 +229,49 @@ func main() {
 func generateMockEmails() []types.Email {
 	return []types.Email{
 		{
-			ID:       "1",
-			From:     "noreply@company.com",
-			Subject:  "感谢您投递简历 - 软件工程师职位",
-			Date:     time.Now().AddDate(0, 0, -1),
-			BodyText: "感谢您投递我们公司软件工程师职位的简历。我们已收到您的申请，将在3-5个工作日内回复。",
-			BodyHTML: "<p>感谢您投递我们公司软件工程师职位的简历。我们已收到您的申请，将在3-5个工作日内回复。</p>",
-			Folder:   "INBOX",
+			ID:        "1",
+			MessageID: "<mock-1@jobtracker.local>",
+			From:      "noreply@company.com",
+			Subject:   "感谢您投递简历 - 软件工程师职位",
+			Date:      time.Now().AddDate(0, 0, -1),
+			BodyText:  "感谢您投递我们公司软件工程师职位的简历。我们已收到您的申请，将在3-5个工作日内回复。",
+			BodyHTML:  "<p>感谢您投递我们公司软件工程师职位的简历。我们已收到您的申请，将在3-5个工作日内回复。</p>",
+			Folder:    "INBOX",
 		},
 		{
-			ID:       "2",
-			From:     "hr@techcorp.com",
-			Subject:  "邀请您参加在线技术测试",
-			Date:     time.Now().AddDate(0, 0, -3),
-			BodyText: "恭喜您通过简历筛选！我们邀请您参加在线技术测试，请在48小时内完成。",
-			BodyHTML: "<p>恭喜您通过简历筛选！我们邀请您参加在线技术测试，请在48小时内完成。</p>",
-			Folder:   "INBOX",
+			ID:        "2",
+			MessageID: "<mock-2@jobtracker.local>",
+			From:      "hr@techcorp.com",
+			Subject:   "邀请您参加在线技术测试",
+			Date:      time.Now().AddDate(0, 0, -3),
+			BodyText:  "恭喜您通过简历筛选！我们邀请您参加在线技术测试，请在48小时内完成。",
+			BodyHTML:  "<p>恭喜您通过简历筛选！我们邀请您参加在线技术测试，请在48小时内完成。</p>",
+			Folder:    "INBOX",
 		},
 		{
-			ID:       "3",
-			From:     "recruitment@startup.io",
-			Subject:  "Interview Invitation - Frontend Developer Position",
-			Date:     time.Now().AddDate(0, 0, -5),
-			BodyText: "We would like to invite you for an interview for the Frontend Developer position. Please confirm your availability.",
-			BodyHTML: "<p>We would like to invite you for an interview for the Frontend Developer position. Please confirm your availability.</p>",
-			Folder:   "INBOX",
+			ID:        "3",
+			MessageID: "<mock-3@jobtracker.local>",
+			From:      "recruitment@startup.io",
+			Subject:   "Interview Invitation - Frontend Developer Position",
+			Date:      time.Now().AddDate(0, 0, -5),
+			BodyText:  "We would like to invite you for an interview for the Frontend Developer position. Please confirm your availability.",
+			BodyHTML:  "<p>We would like to invite you for an interview for the Frontend Developer position. Please confirm your availability.</p>",
+			Folder:    "INBOX",
 		},
 	}
 }
 
 // 打开浏览器
-func openBrowser(url string) error {
+func openBrowser(progress io.Writer, url string) error {
 	// 这个函数的实现可以移到内部包中，这里简化处理
-	fmt.Printf("请手动打开浏览器访问: %s\n", url)
+	fmt.Fprintf(progress, "请手动打开浏览器访问: %s\n", url)
 	return nil
 }
+
+// withExtension 选择非CSV格式时，把默认的 .csv 导出文件名换成对应扩展名（如 emails.csv -> emails.json）
+func withExtension(path, ext string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return strings.TrimSuffix(path, filepath.Ext(path)) + ext
+	}
+	return path
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// runList 实现 `jobtracker list`：按最近邮件日期列出申请
+func runList(opts *options, args []string) {
+	fs := opts.flagSet("list", "")
+	status := fs.String("status", "", "只列出当前状态为指定值的申请（如 INTERVIEW）")
+	company := fs.String("company", "", "只列出公司名包含指定文字的申请")
+	fs.Parse(args)
+
+	cfg := loadConfig(opts)
+	applications := listApplications(cfg)
+
+	wantStatus := types.Status(strings.ToUpper(strings.TrimSpace(*status)))
+	wantCompany := strings.ToLower(strings.TrimSpace(*company))
+
+	count := 0
+	for _, app := range applications {
+		if wantStatus != "" && app.Status != wantStatus {
+			continue
+		}
+		if wantCompany != "" && !strings.Contains(strings.ToLower(app.Company), wantCompany) {
+			continue
+		}
+		latest := app.Latest()
+		if latest == nil {
+			continue
+		}
+		fmt.Printf("%s  %-10s %s - %s  [%s]\n", latest.Date.Format("2006-01-02"), app.Status, app.Company, app.Position, app.Key)
+		count++
+	}
+	fmt.Printf("\n共 %d 个申请（申请库中共 %d 个）\n", count, len(applications))
+}
+
+// runShow 实现 `jobtracker show`：按申请键或关键词查看申请的邮件、状态时间线和日程
+func runShow(opts *options, args []string) {
+	fs := opts.flagSet("show", "<申请键或关键词>")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	query := strings.Join(fs.Args(), " ")
+
+	cfg := loadConfig(opts)
+	applications := listApplications(cfg)
+	matches := findApplications(applications, query)
+
+	switch len(matches) {
+	case 0:
+		fmt.Printf("没有找到与 %q 匹配的申请\n", query)
+		os.Exit(1)
+	case 1:
+		printApplication(matches[0])
+	default:
+		fmt.Printf("有 %d 个申请与 %q 匹配，请使用申请键指定:\n", len(matches), query)
+		for _, app := range matches {
+			fmt.Printf("  %s - %s (%s)  [%s]\n", app.Company, app.Position, app.Status, app.Key)
+		}
+		os.Exit(1)
+	}
+}
+
+// findApplications 申请键完全匹配时只返回该申请，否则返回公司或职位包含关键词的申请
+func findApplications(applications []store.Application, query string) []store.Application {
+	for _, app := range applications {
+		if app.Key == query {
+			return []store.Application{app}
+		}
+	}
+
+	keyword := strings.ToLower(strings.TrimSpace(query))
+	var matches []store.Application
+	for _, app := range applications {
+		if strings.Contains(strings.ToLower(app.Company), keyword) || strings.Contains(strings.ToLower(app.Position), keyword) {
+			matches = append(matches, app)
+		}
+	}
+	return matches
+}
+
+func printApplication(app store.Application) {
+	fmt.Printf("%s - %s\n", app.Company, app.Position)
+	fmt.Printf("  申请键: %s\n", app.Key)
+	if app.RequisitionID != "" {
+		fmt.Printf("  职位编号: %s\n", app.RequisitionID)
+	}
+	if app.Location != "" {
+		fmt.Printf("  地点: %s\n", app.Location)
+	}
+	fmt.Printf("  当前状态: %s\n", app.Status)
+	if rounds := app.Rounds(); rounds > 0 {
+		fmt.Printf("  面试轮数: %d\n", rounds)
+	}
+	if timeline := app.TimelineString(); timeline != "" {
+		fmt.Printf("  时间线: %s\n", timeline)
+	}
+	for _, t := range app.Flagged() {
+		fmt.Printf("  ⚠️  %s（%s）\n", t.Note, t.Subject)
+	}
+
+	fmt.Printf("\n邮件（%d 封）:\n", len(app.Emails))
+	for _, e := range app.Emails {
+		fmt.Printf("• %s  %-10s %s\n", e.Date.Format("2006-01-02 15:04"), e.Status, e.Subject)
+		fmt.Printf("    发件人: %s\n", e.From)
+		if e.Description != "" {
+			fmt.Printf("    %s\n", e.Description)
+		}
+		if !e.EventStart.IsZero() {
+			fmt.Printf("    日程: %s\n", formatEventTime(e.EventStart, e.EventEnd, e.Timezone))
+		}
+		if !e.Deadline.IsZero() {
+			fmt.Printf("    截止: %s\n", e.Deadline.Format("2006-01-02 15:04"))
+		}
+		if e.MeetingURL != "" {
+			fmt.Printf("    会议链接: %s\n", e.MeetingURL)
+		}
+	}
+}
+
+// formatEventTime 按邮件中的时区显示日程时间，时区无效时按本地时间显示
+func formatEventTime(start, end time.Time, timezone string) string {
+	if loc, err := time.LoadLocation(timezone); timezone != "" && err == nil {
+		start, end = start.In(loc), end.In(loc)
+	}
+	s := start.Format("2006-01-02 15:04")
+	if !end.IsZero() {
+		s += " - " + end.Format("15:04")
+	}
+	if timezone != "" {
+		s += " (" + timezone + ")"
+	}
+	return s
+}
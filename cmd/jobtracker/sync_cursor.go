@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 增量同步游标按邮箱保存在数据目录中。fetchAndSave 把邮件写入邮件缓存后立即更新，
+// 不等分析和导出：之后的步骤失败时邮件已在缓存中，下次运行会作为未分析的邮件重新处理，而不是重新获取
+func syncCursorPath(dataDir string) string {
+	return filepath.Join(dataDir, "sync_cursors.json")
+}
+
+func loadSyncCursor(dataDir, email string) (string, error) {
+	cursors, err := readSyncCursors(dataDir)
+	if err != nil {
+		return "", err
+	}
+	return cursors[strings.ToLower(email)], nil
+}
+
+func saveSyncCursor(dataDir, email, cursor string) error {
+	cursors, err := readSyncCursors(dataDir)
+	if err != nil {
+		return err
+	}
+	cursors[strings.ToLower(email)] = cursor
+
+	data, err := json.MarshalIndent(cursors, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal sync cursors: %w", err)
+	}
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return fmt.Errorf("create data dir: %w", err)
+	}
+	return os.WriteFile(syncCursorPath(dataDir), data, 0o600)
+}
+
+func readSyncCursors(dataDir string) (map[string]string, error) {
+	cursors := make(map[string]string)
+
+	data, err := os.ReadFile(syncCursorPath(dataDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return cursors, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read sync cursors: %w", err)
+	}
+	if err := json.Unmarshal(data, &cursors); err != nil {
+		return nil, fmt.Errorf("parse sync cursors: %w", err)
+	}
+	return cursors, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CalendarInvite 邮件中 text/calendar 部分的一个事件（如 METHOD:REQUEST 面试邀请）
+type CalendarInvite struct {
+	Method     string    `json:"method,omitempty"`
+	UID        string    `json:"uid,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end,omitempty"`
+	Timezone   string    `json:"timezone,omitempty"`
+	Location   string    `json:"location,omitempty"`
+	MeetingURL string    `json:"meeting_url,omitempty"`
+	Organizer  string    `json:"organizer,omitempty"`
+}
+
+// Outlook/Exchange 邀请常用Windows时区名，映射到IANA名称
+var windowsZones = map[string]string{
+	"China Standard Time":       "Asia/Shanghai",
+	"Taipei Standard Time":      "Asia/Taipei",
+	"Tokyo Standard Time":       "Asia/Tokyo",
+	"Singapore Standard Time":   "Asia/Singapore",
+	"India Standard Time":       "Asia/Kolkata",
+	"GMT Standard Time":         "Europe/London",
+	"W. Europe Standard Time":   "Europe/Berlin",
+	"Romance Standard Time":     "Europe/Paris",
+	"Eastern Standard Time":     "America/New_York",
+	"Central Standard Time":     "America/Chicago",
+	"Mountain Standard Time":    "America/Denver",
+	"Pacific Standard Time":     "America/Los_Angeles",
+	"AUS Eastern Standard Time": "Australia/Sydney",
+}
+
+// 常见视频会议链接
+var meetingURLPattern = regexp.MustCompile(`https?://[^\s"<>\\]*(zoom\.us|teams\.microsoft\.com|teams\.live\.com|meet\.google\.com|webex\.com|meeting\.tencent\.com|voovmeeting\.com|feishu\.cn|larksuite\.com|dingtalk\.com)[^\s"<>\\]*`)
+
+// parseCalendar 解析iCalendar文本中的VEVENT，忽略无法识别的属性和嵌套组件（如VALARM）
+func parseCalendar(data string) []CalendarInvite {
+	// 展开折行（CRLF或LF后接空格/制表符）
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.NewReplacer("\n ", "", "\n\t", "").Replace(data)
+
+	var (
+		invites []CalendarInvite
+		method  string
+		current *CalendarInvite
+		depth   int // VEVENT内嵌套组件的层数
+		desc    string
+	)
+
+	for _, line := range strings.Split(data, "\n") {
+		name, params, value := parseContentLine(line)
+		switch {
+		case name == "":
+			continue
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current, depth, desc = &CalendarInvite{Method: method}, 0, ""
+			continue
+		case current == nil:
+			if name == "METHOD" {
+				method = strings.ToUpper(value)
+			}
+			continue
+		case name == "BEGIN":
+			depth++
+			continue
+		case name == "END" && depth > 0:
+			depth--
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current.MeetingURL == "" {
+				current.MeetingURL = findMeetingURL(current.Location + "\n" + desc)
+			}
+			if !current.Start.IsZero() {
+				invites = append(invites, *current)
+			}
+			current = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch name {
+		case "UID":
+			current.UID = value
+		case "SUMMARY":
+			current.Summary = unescapeText(value)
+		case "LOCATION":
+			current.Location = unescapeText(value)
+		case "DESCRIPTION":
+			desc = unescapeText(value)
+		case "URL", "X-GOOGLE-CONFERENCE", "X-MICROSOFT-SKYPETEAMSMEETINGURL":
+			if current.MeetingURL == "" {
+				current.MeetingURL = value
+			}
+		case "ORGANIZER":
+			current.Organizer = strings.TrimPrefix(strings.TrimPrefix(value, "mailto:"), "MAILTO:")
+		case "DTSTART":
+			current.Start, current.Timezone = parseCalendarTime(value, params["TZID"])
+		case "DTEND":
+			current.End, _ = parseCalendarTime(value, params["TZID"])
+		}
+	}
+
+	return invites
+}
+
+// parseContentLine 解析 "NAME;PARAM=VALUE:value" 格式的内容行
+func parseContentLine(line string) (name string, params map[string]string, value string) {
+	line = strings.TrimRight(line, "\r")
+
+	// 冒号可能出现在带引号的参数值里
+	inQuote := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuote = !inQuote
+		} else if r == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, ""
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params = make(map[string]string)
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+// parseCalendarTime 解析 DATE-TIME（UTC、带TZID或浮动时间）和 DATE 值，返回时间和时区名
+func parseCalendarTime(value, tzid string) (time.Time, string) {
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, ""
+		}
+		return t, "UTC"
+	}
+
+	loc := time.UTC
+	if tzid != "" {
+		name := tzid
+		if iana, ok := windowsZones[tzid]; ok {
+			name = iana
+		}
+		if l, err := time.LoadLocation(name); err == nil {
+			loc = l
+			tzid = name
+		}
+	}
+
+	for _, layout := range []string{"20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, tzid
+		}
+	}
+	return time.Time{}, ""
+}
+
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// findMeetingURL 从地点或描述中找出视频会议链接
+func findMeetingURL(text string) string {
+	return meetingURLPattern.FindString(text)
+}
+
+// dedupeInvites 同一邀请可能同时以内联和附件形式出现，按UID和开始时间去重
+func dedupeInvites(invites []CalendarInvite) []CalendarInvite {
+	seen := make(map[string]bool)
+	var out []CalendarInvite
+	for _, inv := range invites {
+		key := inv.UID + "|" + inv.Start.UTC().Format(time.RFC3339)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, inv)
+	}
+	return out
+}
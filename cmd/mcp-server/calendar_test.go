@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCalendarOutlookInvite(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+	// Outlook 风格：Windows时区名、折行的描述（CRLF后接空格或制表符）、嵌套VALARM
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"METHOD:REQUEST",
+		"BEGIN:VTIMEZONE",
+		"TZID:China Standard Time",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:040000008200E00074C5B7101A82E008",
+		"SUMMARY;LANGUAGE=zh-CN:技术面试\\, 第二轮",
+		"DTSTART;TZID=China Standard Time:20250318T140000",
+		"DTEND;TZID=\"China Standard Time\":20250318T150000",
+		"ORGANIZER;CN=\"Recruiting: Acme\":MAILTO:recruiting@acme.com",
+		"LOCATION:Microsoft Teams 会议",
+		"DESCRIPTION:点击加入会议\\n https://teams.microsoft.com/l/meetup-join/19%3am",
+		" eeting_abc/0?context=%7b%7d\\nMeeting ID: 123",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"DESCRIPTION:Reminder https://zoom.us/j/999",
+		"END:VALARM",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	invites := parseCalendar(ics)
+	if len(invites) != 1 {
+		t.Fatalf("got %d invites, want 1", len(invites))
+	}
+	inv := invites[0]
+	want := CalendarInvite{
+		Method:     "REQUEST",
+		UID:        "040000008200E00074C5B7101A82E008",
+		Summary:    "技术面试, 第二轮",
+		Start:      time.Date(2025, 3, 18, 14, 0, 0, 0, shanghai),
+		End:        time.Date(2025, 3, 18, 15, 0, 0, 0, shanghai),
+		Timezone:   "Asia/Shanghai",
+		Location:   "Microsoft Teams 会议",
+		MeetingURL: "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc/0?context=%7b%7d",
+		Organizer:  "recruiting@acme.com",
+	}
+	if !inv.Start.Equal(want.Start) || !inv.End.Equal(want.End) {
+		t.Errorf("start/end = %v/%v, want %v/%v", inv.Start, inv.End, want.Start, want.End)
+	}
+	inv.Start, inv.End = want.Start, want.End
+	if inv != want {
+		t.Errorf("invite =\n%+v\nwant\n%+v", inv, want)
+	}
+}
+
+func TestParseCalendarTimes(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+	tests := []struct {
+		name      string
+		dtstart   string
+		wantStart time.Time
+		wantTZ    string
+	}{
+		{"utc", "DTSTART:20250315T020000Z", time.Date(2025, 3, 15, 2, 0, 0, 0, time.UTC), "UTC"},
+		{"iana tzid", "DTSTART;TZID=America/New_York:20250310T090000", time.Date(2025, 3, 10, 9, 0, 0, 0, newYork), "America/New_York"},
+		{"windows tzid", "DTSTART;TZID=Eastern Standard Time:20250310T090000", time.Date(2025, 3, 10, 9, 0, 0, 0, newYork), "America/New_York"},
+		{"unknown tzid", "DTSTART;TZID=Customized Time Zone:20250310T090000", time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), "Customized Time Zone"},
+		{"floating", "DTSTART:20250310T090000", time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), ""},
+		{"all day", "DTSTART;VALUE=DATE:20250320", time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC), ""},
+		{"all day with tzid", "DTSTART;VALUE=DATE;TZID=America/New_York:20250320", time.Date(2025, 3, 20, 0, 0, 0, 0, newYork), "America/New_York"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\n" + tt.dtstart + "\nEND:VEVENT\nEND:VCALENDAR\n"
+			invites := parseCalendar(ics)
+			if len(invites) != 1 {
+				t.Fatalf("got %d invites, want 1", len(invites))
+			}
+			if got := invites[0]; !got.Start.Equal(tt.wantStart) || got.Timezone != tt.wantTZ {
+				t.Errorf("start = %v (%q), want %v (%q)", got.Start, got.Timezone, tt.wantStart, tt.wantTZ)
+			}
+		})
+	}
+}
+
+func TestParseCalendarEvents(t *testing.T) {
+	// 没有METHOD的日历、缺少DTSTART的事件、以LF结尾并用制表符折行的内容
+	ics := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:no-start\nSUMMARY:Placeholder\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:a\nSUMMARY:Phone\n\t screen\nDTSTART:20250401T010000Z\n" +
+		"URL:https://example.com/event\nLOCATION:https://zoom.us/j/123\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:b\nDTSTART:20250402T010000Z\nLOCATION:https://meet.google.com/abc-defg-hij\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:a\nDTSTART:20250401T010000Z\nEND:VEVENT\n" +
+		"END:VCALENDAR\n"
+
+	invites := parseCalendar(ics)
+	if len(invites) != 3 {
+		t.Fatalf("got %d invites, want 3 (event without DTSTART dropped)", len(invites))
+	}
+	if invites[0].Summary != "Phone screen" || invites[0].Method != "" {
+		t.Errorf("first invite = %+v", invites[0])
+	}
+	// 显式的URL属性优先于地点中的链接
+	if invites[0].MeetingURL != "https://example.com/event" {
+		t.Errorf("meeting URL = %q, want the URL property", invites[0].MeetingURL)
+	}
+	if invites[1].MeetingURL != "https://meet.google.com/abc-defg-hij" {
+		t.Errorf("meeting URL = %q, want the link from LOCATION", invites[1].MeetingURL)
+	}
+
+	deduped := dedupeInvites(invites)
+	if len(deduped) != 2 || deduped[0].UID != "a" || deduped[1].UID != "b" {
+		t.Errorf("deduped = %+v, want a and b once each", deduped)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-sasl"
+)
+
+// xoauth2 是Gmail和Outlook使用的非标准SASL机制，go-sasl 只内置了标准的 OAUTHBEARER
+const xoauth2 = "XOAUTH2"
+
+type xoauth2Client struct {
+	username string
+	token    string
+}
+
+// newXOAuth2Client 创建 XOAUTH2 SASL客户端
+func newXOAuth2Client(username, token string) sasl.Client {
+	return &xoauth2Client{username: username, token: token}
+}
+
+func (a *xoauth2Client) Start() (string, []byte, error) {
+	ir := "user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"
+	return xoauth2, []byte(ir), nil
+}
+
+// Next 认证失败时服务器会返回一段JSON错误描述，按协议需回复空响应后服务器才会结束命令
+func (a *xoauth2Client) Next(challenge []byte) ([]byte, error) {
+	var authErr struct {
+		Status string `json:"status"`
+		Scope  string `json:"scope"`
+	}
+	if err := json.Unmarshal(challenge, &authErr); err == nil && authErr.Status != "" {
+		return []byte{}, fmt.Errorf("XOAUTH2 authentication error (%s)", authErr.Status)
+	}
+	return []byte{}, nil
+}
+
+// authenticateIMAP 优先使用OAuth令牌进行SASL认证（OAUTHBEARER或XOAUTH2），
+// 该提供商未配置OAuth或账号尚未授权时回退到应用密码登录
+func (s *MCPServer) authenticateIMAP(ctx context.Context, c *client.Client, provider, account, host string) error {
+	if _, ok := s.oauthConfigs[provider]; ok && s.hasOAuthToken(account) {
+		token, err := s.accessToken(ctx, provider, account)
+		if err != nil {
+			return err
+		}
+
+		auth, err := s.saslClient(c, account, token.AccessToken, host)
+		if err != nil {
+			return err
+		}
+		if err := c.Authenticate(auth); err != nil {
+			return fmt.Errorf("IMAP OAuth认证失败: %v", err)
+		}
+		return nil
+	}
+
+	// 从环境变量获取密码
+	password := os.Getenv("EMAIL_PASSWORD")
+	if password == "" {
+		// 尝试使用应用密码
+		password = os.Getenv("EMAIL_APP_PASSWORD")
+	}
+
+	if password == "" {
+		if provider == "gmail" || provider == "outlook" {
+			return fmt.Errorf("账号 %s 尚未完成OAuth授权，请先调用 email.login，或设置环境变量 EMAIL_APP_PASSWORD", account)
+		}
+		return fmt.Errorf("请设置环境变量 EMAIL_PASSWORD 或 EMAIL_APP_PASSWORD")
+	}
+
+	if err := c.Login(account, password); err != nil {
+		return fmt.Errorf("IMAP登录失败: %v", err)
+	}
+	return nil
+}
+
+// saslClient 根据服务器能力选择SASL机制：优先标准的 OAUTHBEARER（RFC 7628），否则使用 XOAUTH2
+func (s *MCPServer) saslClient(c *client.Client, username, token, host string) (sasl.Client, error) {
+	if ok, _ := c.SupportAuth(sasl.OAuthBearer); ok {
+		hostname, portStr, _ := net.SplitHostPort(host)
+		port, _ := strconv.Atoi(portStr)
+		return sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{
+			Username: username,
+			Token:    token,
+			Host:     hostname,
+			Port:     port,
+		}), nil
+	}
+
+	if ok, _ := c.SupportAuth(xoauth2); ok {
+		return newXOAuth2Client(username, token), nil
+	}
+
+	return nil, fmt.Errorf("IMAP服务器 %s 不支持 OAUTHBEARER 或 XOAUTH2 认证", host)
+}
@@ -1,34 +1,39 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
-	"github.com/emersion/go-message"
 	"golang.org/x/oauth2"
 )
 
-// MCP协议结构体
+// MCP协议结构体（JSON-RPC 2.0），ID 可能是字符串或数字，通知消息没有 ID
 type MCPRequest struct {
-	Jsonrpc string      `json:"jsonrpc"`
-	ID      string      `json:"id"`
-	Method  string      `json:"method"`
-	Params  interface{} `json:"params"`
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
 }
 
 type MCPResponse struct {
-	Jsonrpc string      `json:"jsonrpc"`
-	ID      string      `json:"id"`
-	Result  interface{} `json:"result,omitempty"`
-	Error   *MCPError   `json:"error,omitempty"`
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
 }
 
 type MCPError struct {
@@ -46,6 +51,8 @@ type Email struct {
 	BodyHTML  string    `json:"body_html"`
 	MessageID string    `json:"message_id"`
 	Folder    string    `json:"folder"`
+	// text/calendar 部分解析出的日历邀请
+	Invites []CalendarInvite `json:"invites,omitempty"`
 }
 
 type LoginParams struct {
@@ -60,7 +67,12 @@ type FetchParams struct {
 	EndDate   time.Time `json:"end_date"`
 	MaxEmails int       `json:"max_emails"`
 	Folders   []string  `json:"folders"`
-	Keywords  []string  `json:"keywords"`
+	// 筛选条件：关键词、发件人域名、主题片段，任一匹配即可
+	Keywords        []string `json:"keywords"`
+	SenderDomains   []string `json:"sender_domains"`
+	SubjectPatterns []string `json:"subject_patterns"`
+	// 上一页返回的 next_page_token
+	PageToken string `json:"page_token"`
 }
 
 type LoginSession struct {
@@ -68,287 +80,351 @@ type LoginSession struct {
 	LoginURL  string `json:"login_url"`
 	Status    string `json:"status"`
 	Message   string `json:"message"`
+
+	provider  string
+	account   string
+	state     string
+	verifier  string
+	createdAt time.Time
 }
 
+// 每次FETCH命令获取的邮件数量
+const fetchBatchSize = 50
+
 // MCP服务器
 type MCPServer struct {
-	sessions map[string]*LoginSession
-	tokens   map[string]*oauth2.Token
+	mu           sync.Mutex
+	sessions     map[string]*LoginSession
+	tokens       map[string]*oauth2.Token
+	tokenStore   *TokenStore
+	checkpoints  *CheckpointStore
+	oauthConfigs map[string]*oauth2.Config
+	mcp          mcpState
 }
 
 func NewMCPServer() *MCPServer {
 	return &MCPServer{
-		sessions: make(map[string]*LoginSession),
-		tokens:   make(map[string]*oauth2.Token),
+		sessions:     make(map[string]*LoginSession),
+		tokens:       make(map[string]*oauth2.Token),
+		tokenStore:   NewTokenStore(dataDir()),
+		checkpoints:  NewCheckpointStore(dataDir()),
+		oauthConfigs: newOAuthConfigs(),
 	}
 }
 
 func main() {
-	server := NewMCPServer()
+	transport := flag.String("transport", "http", "传输方式: http 或 stdio")
+	addr := flag.String("addr", ":8080", "HTTP监听地址（stdio模式下仅用于OAuth回调）")
+	flag.Parse()
 
-	http.HandleFunc("/mcp", server.handleMCP)
-	http.HandleFunc("/oauth/callback", server.handleOAuthCallback)
+	// 日志统一写到标准错误，stdio模式下标准输出只用于协议消息
+	log.SetOutput(os.Stderr)
 
-	fmt.Println("🚀 MCP服务器启动在 http://localhost:8080")
-	fmt.Println("📧 支持的邮箱提供商: Gmail, Outlook, Yahoo, 自定义IMAP")
-	fmt.Println("🔐 Gmail需要OAuth认证，其他可使用应用密码")
-
-	log.Fatal(http.ListenAndServe(":8080", nil))
-}
+	server := NewMCPServer()
 
-func (s *MCPServer) handleMCP(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	switch *transport {
+	case "stdio":
+		// OAuth回调仍需要HTTP监听，端口被占用时只影响浏览器授权
+		callbackMux := http.NewServeMux()
+		callbackMux.HandleFunc("/oauth/callback", server.handleOAuthCallback)
+		go func() {
+			if err := http.ListenAndServe(*addr, callbackMux); err != nil {
+				log.Printf("OAuth回调监听失败: %v", err)
+			}
+		}()
+
+		log.Println("🚀 MCP服务器以stdio模式启动")
+		if err := server.serveStdio(context.Background(), os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("stdio传输出错: %v", err)
+		}
 
-	if r.Method == "OPTIONS" {
-		return
-	}
+	case "http":
+		http.HandleFunc("/mcp", server.handleMCP)
+		http.HandleFunc("/oauth/callback", server.handleOAuthCallback)
 
-	var req MCPRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.sendError(w, req.ID, -32700, "Parse error")
-		return
-	}
+		fmt.Printf("🚀 MCP服务器启动在 http://localhost%s\n", *addr)
+		fmt.Println("📧 支持的邮箱提供商: Gmail, Outlook, Yahoo, 自定义IMAP")
+		fmt.Println("🔐 Gmail需要OAuth认证，其他可使用应用密码")
 
-	var response interface{}
-	var err error
+		log.Fatal(http.ListenAndServe(*addr, nil))
 
-	switch req.Method {
-	case "email.login":
-		response, err = s.handleLogin(req.Params)
-	case "email.fetch":
-		response, err = s.handleFetch(req.Params)
 	default:
-		s.sendError(w, req.ID, -32601, "Method not found")
-		return
-	}
-
-	if err != nil {
-		s.sendError(w, req.ID, -32000, err.Error())
-		return
-	}
-
-	resp := MCPResponse{
-		Jsonrpc: "2.0",
-		ID:      req.ID,
-		Result:  response,
+		log.Fatalf("未知的传输方式: %s（可选 http 或 stdio）", *transport)
 	}
-
-	json.NewEncoder(w).Encode(resp)
 }
 
-func (s *MCPServer) handleLogin(params interface{}) (*LoginSession, error) {
+func (s *MCPServer) handleLogin(params json.RawMessage) (*LoginSession, error) {
 	var loginParams LoginParams
-	data, _ := json.Marshal(params)
-	if err := json.Unmarshal(data, &loginParams); err != nil {
+	if err := json.Unmarshal(params, &loginParams); err != nil {
 		return nil, fmt.Errorf("invalid login parameters")
 	}
 
-	sessionID := fmt.Sprintf("session_%d", time.Now().Unix())
+	session := &LoginSession{
+		SessionID: "session_" + randomToken(),
+		provider:  loginParams.Provider,
+		account:   loginParams.Email,
+		createdAt: time.Now(),
+	}
 
-	switch loginParams.Provider {
-	case "gmail":
-		// Gmail使用OAuth2流程
-		session := &LoginSession{
-			SessionID: sessionID,
-			LoginURL:  s.generateGmailOAuthURL(sessionID),
-			Status:    "pending",
-			Message:   "请在浏览器中完成Google OAuth认证",
-		}
-		s.sessions[sessionID] = session
-		return session, nil
+	_, hasOAuth := s.oauthConfigs[loginParams.Provider]
+
+	switch {
+	case hasOAuth && s.hasOAuthToken(loginParams.Email):
+		session.Status = "ready"
+		session.Message = "已有OAuth授权，无需重新登录"
+
+	case loginParams.Provider == "gmail" || hasOAuth:
+		// Gmail以及配置了Azure应用的Outlook使用OAuth2流程
+		session.LoginURL = s.startOAuth(session)
+		session.Status = "pending"
+		session.Message = "请在浏览器中完成OAuth认证"
 
 	default:
 		// 其他邮箱使用应用密码
-		session := &LoginSession{
-			SessionID: sessionID,
-			LoginURL:  "",
-			Status:    "ready",
-			Message:   "使用应用密码认证，无需浏览器登录",
-		}
-		s.sessions[sessionID] = session
-		return session, nil
+		session.Status = "ready"
+		session.Message = "使用应用密码认证，无需浏览器登录"
 	}
+
+	s.mu.Lock()
+	s.sessions[session.SessionID] = session
+	s.mu.Unlock()
+
+	return session, nil
 }
 
-func (s *MCPServer) handleFetch(params interface{}) ([]Email, error) {
+func (s *MCPServer) handleFetch(ctx context.Context, params json.RawMessage) (*FetchResult, error) {
 	var fetchParams FetchParams
-	data, _ := json.Marshal(params)
-	if err := json.Unmarshal(data, &fetchParams); err != nil {
+	if err := json.Unmarshal(params, &fetchParams); err != nil {
 		return nil, fmt.Errorf("invalid fetch parameters")
 	}
 
-	switch fetchParams.Provider {
+	return s.fetchEmails(ctx, fetchParams)
+}
+
+// fetchEmails 根据提供商选择IMAP主机并抓取一页邮件
+func (s *MCPServer) fetchEmails(ctx context.Context, params FetchParams) (*FetchResult, error) {
+	if params.MaxEmails <= 0 {
+		params.MaxEmails = 50
+	}
+	if len(params.Folders) == 0 {
+		params.Folders = []string{"INBOX"}
+	}
+	return s.fetchIMAPEmails(ctx, params, s.imapHost(params.Provider, params.Email))
+}
+
+// imapHost 返回提供商对应的IMAP主机，自定义邮箱根据域名推断
+func (s *MCPServer) imapHost(provider, email string) string {
+	switch provider {
 	case "gmail":
-		return s.fetchGmailEmails(fetchParams)
+		return "imap.gmail.com:993"
 	case "outlook":
-		return s.fetchIMAPEmails(fetchParams, "outlook.office365.com:993")
+		return "outlook.office365.com:993"
 	case "yahoo":
-		return s.fetchIMAPEmails(fetchParams, "imap.mail.yahoo.com:993")
+		return "imap.mail.yahoo.com:993"
 	default:
-		return s.fetchIMAPEmails(fetchParams, s.inferIMAPHost(fetchParams.Email))
+		return s.inferIMAPHost(email)
 	}
 }
 
-func (s *MCPServer) generateGmailOAuthURL(sessionID string) string {
-	// 简化的OAuth URL生成
-	// 实际应用中需要配置Google OAuth客户端
-	clientID := os.Getenv("GMAIL_CLIENT_ID")
-	if clientID == "" {
-		return "https://console.cloud.google.com/apis/credentials (请配置GMAIL_CLIENT_ID)"
+// connectIMAP 连接并登录IMAP服务器。ctx 被取消时直接断开连接以打断进行中的命令，
+// 调用方用完后需调用返回的 closeFn
+func (s *MCPServer) connectIMAP(ctx context.Context, provider, account, host string) (*client.Client, func(), error) {
+	if host == "" {
+		return nil, nil, fmt.Errorf("无法推断IMAP主机，请配置自定义主机")
 	}
 
-	redirectURI := "http://localhost:8080/oauth/callback"
-	scope := "https://www.googleapis.com/auth/gmail.readonly"
-
-	return fmt.Sprintf(
-		"https://accounts.google.com/o/oauth2/auth?client_id=%s&redirect_uri=%s&scope=%s&response_type=code&state=%s",
-		clientID, redirectURI, scope, sessionID,
-	)
-}
-
-func (s *MCPServer) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
-	code := r.URL.Query().Get("code")
-	state := r.URL.Query().Get("state")
+	// 连接IMAP
+	c, err := client.DialTLS(host, &tls.Config{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("连接IMAP服务器失败: %v", err)
+	}
 
-	if session, exists := s.sessions[state]; exists {
-		session.Status = "completed"
-		session.Message = "OAuth认证完成"
-		// 这里应该用code换取access token
-		// 简化实现，实际需要完整的OAuth流程
-		_ = code // 避免未使用变量警告
+	// 登录（OAuth SASL 或应用密码）
+	if err := s.authenticateIMAP(ctx, c, provider, account, host); err != nil {
+		c.Terminate()
+		return nil, nil, err
 	}
 
-	fmt.Fprintf(w, "<h2>认证完成</h2><p>可以关闭此页面，返回应用程序。</p>")
-}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Terminate()
+		case <-stop:
+		}
+	}()
 
-func (s *MCPServer) fetchGmailEmails(params FetchParams) ([]Email, error) {
-	// Gmail API 实现
-	// 这里简化为返回模拟数据，实际需要集成Gmail API
-	return []Email{
-		{
-			ID:       "gmail_1",
-			From:     "noreply@google.com",
-			Subject:  "Gmail API 测试邮件",
-			Date:     time.Now(),
-			BodyText: "这是通过Gmail API获取的测试邮件",
-			Folder:   "INBOX",
-		},
-	}, nil
+	closeFn := func() {
+		close(stop)
+		c.Logout()
+	}
+	return c, closeFn, nil
 }
 
-func (s *MCPServer) fetchIMAPEmails(params FetchParams, host string) ([]Email, error) {
-	if host == "" {
-		return nil, fmt.Errorf("无法推断IMAP主机，请配置自定义主机")
+func (s *MCPServer) fetchIMAPEmails(ctx context.Context, params FetchParams, host string) (*FetchResult, error) {
+	var token *pageToken
+	if params.PageToken != "" {
+		var err error
+		if token, err = decodePageToken(params.PageToken, params); err != nil {
+			return nil, invalidParams(err.Error())
+		}
+	} else {
+		token = &pageToken{}
 	}
 
-	// 从环境变量获取密码
-	password := os.Getenv("EMAIL_PASSWORD")
-	if password == "" {
-		// 尝试使用应用密码
-		password = os.Getenv("EMAIL_APP_PASSWORD")
+	c, closeFn, err := s.connectIMAP(ctx, params.Provider, params.Email, host)
+	if err != nil {
+		return nil, err
 	}
+	defer closeFn()
 
-	if password == "" {
-		return nil, fmt.Errorf("请设置环境变量 EMAIL_PASSWORD 或 EMAIL_APP_PASSWORD")
+	limit := params.MaxEmails
+	if limit > maxPageSize {
+		limit = maxPageSize
 	}
 
-	// 连接IMAP
-	c, err := client.DialTLS(host, &tls.Config{})
-	if err != nil {
-		return nil, fmt.Errorf("连接IMAP服务器失败: %v", err)
-	}
-	defer c.Close()
+	result := &FetchResult{Emails: []Email{}}
 
-	// 登录
-	if err := c.Login(params.Email, password); err != nil {
-		return nil, fmt.Errorf("IMAP登录失败: %v", err)
-	}
-	defer c.Logout()
+	// 遍历文件夹，从令牌记录的位置继续
+	for i := token.Folder; i < len(params.Folders); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		folder := params.Folders[i]
+		reportProgress(ctx, 0, fmt.Sprintf("正在抓取文件夹 %s (%d/%d)", folder, i+1, len(params.Folders)))
 
-	var emails []Email
+		var validity, before uint32
+		if i == token.Folder {
+			validity, before = token.UIDValidity, token.BeforeUID
+		}
 
-	// 遍历文件夹
-	for _, folder := range params.Folders {
-		folderEmails, err := s.fetchFromFolder(c, folder, params)
+		page, err := s.fetchFromFolder(ctx, c, folder, params, validity, before, limit-len(result.Emails))
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if errors.Is(err, errPageTokenExpired) {
+				return nil, invalidParams(err.Error())
+			}
 			log.Printf("获取文件夹 %s 失败: %v", folder, err)
 			continue
 		}
-		emails = append(emails, folderEmails...)
+		result.Emails = append(result.Emails, page.emails...)
 
-		if len(emails) >= params.MaxEmails {
+		next := &pageToken{Query: queryFingerprint(params), Folder: i + 1}
+		if page.hasMore {
+			next = &pageToken{Query: next.Query, Folder: i, UIDValidity: page.uidValidity, BeforeUID: page.oldestUID}
+		}
+		if page.hasMore || (len(result.Emails) >= limit && i+1 < len(params.Folders)) {
+			result.NextPageToken = encodePageToken(next)
 			break
 		}
 	}
 
-	// 限制返回数量
-	if len(emails) > params.MaxEmails {
-		emails = emails[:params.MaxEmails]
-	}
+	return result, nil
+}
 
-	return emails, nil
+// folderPage 单个文件夹的一页抓取结果
+type folderPage struct {
+	emails      []Email
+	uidValidity uint32
+	// 本页最旧一封邮件的UID，下一页从它之前开始
+	oldestUID uint32
+	hasMore   bool
 }
 
-func (s *MCPServer) fetchFromFolder(c *client.Client, folder string, params FetchParams) ([]Email, error) {
+// fetchFromFolder 按UID抓取时间范围内最新的 limit 封邮件（从新到旧）。
+// before 非0时只抓取UID小于 before 的邮件；validity 非0时要求文件夹的 UIDVALIDITY 未变化。
+func (s *MCPServer) fetchFromFolder(ctx context.Context, c *client.Client, folder string, params FetchParams, validity, before uint32, limit int) (*folderPage, error) {
 	// 选择文件夹
 	mbox, err := c.Select(folder, true)
 	if err != nil {
 		return nil, err
 	}
-
-	if mbox.Messages == 0 {
-		return nil, nil
+	if validity != 0 && mbox.UidValidity != validity {
+		return nil, errPageTokenExpired
 	}
 
-	// 搜索邮件
-	criteria := imap.NewSearchCriteria()
-	criteria.Since = params.StartDate
-	criteria.Before = params.EndDate.AddDate(0, 0, 1) // 包含结束日期
+	page := &folderPage{uidValidity: mbox.UidValidity}
+	if mbox.Messages == 0 || before == 1 || limit <= 0 {
+		return page, nil
+	}
 
-	uids, err := c.Search(criteria)
+	// 搜索邮件，筛选条件尽量交给服务器执行
+	filter := newSearchFilter(params)
+	uids, postFilter, err := s.searchUIDs(c, baseCriteria(params.StartDate, params.EndDate, before), filter)
 	if err != nil {
 		return nil, err
 	}
-
 	if len(uids) == 0 {
-		return nil, nil
+		return page, nil
 	}
 
-	// 限制获取数量
-	limit := params.MaxEmails
-	if len(uids) < limit {
-		limit = len(uids)
+	// UID越大越新
+	sort.Slice(uids, func(i, j int) bool { return uids[i] > uids[j] })
+	if postFilter {
+		return s.fetchFiltered(ctx, c, folder, uids, filter, limit, page)
 	}
-	uids = uids[:limit]
 
-	// 获取邮件
-	seqset := new(imap.SeqSet)
-	seqset.AddNum(uids...)
+	// 取最新的 limit 封
+	if len(uids) > limit {
+		uids = uids[:limit]
+		page.hasMore = true
+	}
+	page.oldestUID = uids[len(uids)-1]
 
-	messages := make(chan *imap.Message, limit)
-	done := make(chan error, 1)
+	page.emails, err = s.fetchUIDs(ctx, c, folder, uids)
+	if err != nil {
+		return nil, err
+	}
 
-	go func() {
-		done <- c.Fetch(seqset, []imap.FetchItem{imap.FetchEnvelope, imap.FetchBodyStructure}, messages)
-	}()
+	// 服务器按自己的顺序返回FETCH结果，重新按UID从新到旧排列
+	sort.SliceStable(page.emails, func(i, j int) bool {
+		return emailUID(page.emails[i]) > emailUID(page.emails[j])
+	})
 
-	var emails []Email
-	for msg := range messages {
-		email := s.convertToEmail(msg, folder)
-		emails = append(emails, email)
-	}
+	return page, nil
+}
 
-	if err := <-done; err != nil {
-		return nil, err
+// fetchFiltered 从新到旧逐批抓取并在本地过滤，直到凑满 limit 封。
+// 下一页从本页最后一封匹配邮件之前开始，本批中多出的匹配邮件会在下一页重新获取。
+func (s *MCPServer) fetchFiltered(ctx context.Context, c *client.Client, folder string, uids []uint32, filter searchFilter, limit int, page *folderPage) (*folderPage, error) {
+	for start := 0; start < len(uids); start += fetchBatchSize {
+		end := start + fetchBatchSize
+		if end > len(uids) {
+			end = len(uids)
+		}
+
+		batch, err := s.fetchUIDs(ctx, c, folder, uids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(batch, func(i, j int) bool {
+			return emailUID(batch[i]) > emailUID(batch[j])
+		})
+
+		for _, email := range batch {
+			if !filter.match(email) {
+				continue
+			}
+			page.emails = append(page.emails, email)
+			if len(page.emails) == limit {
+				page.oldestUID = emailUID(email)
+				page.hasMore = page.oldestUID > uids[len(uids)-1]
+				return page, nil
+			}
+		}
 	}
+	return page, nil
+}
 
-	return emails, nil
+// emailUID 从邮件ID解析UID
+func emailUID(email Email) uint32 {
+	uid, _ := strconv.ParseUint(email.ID, 10, 32)
+	return uint32(uid)
 }
 
-func (s *MCPServer) convertToEmail(msg *imap.Message, folder string) Email {
+func (s *MCPServer) convertToEmail(msg *imap.Message, folder string, section *imap.BodySectionName) Email {
 	email := Email{
 		ID:     fmt.Sprintf("%d", msg.Uid),
 		Folder: folder,
@@ -363,17 +439,20 @@ func (s *MCPServer) convertToEmail(msg *imap.Message, folder string) Email {
 		email.MessageID = msg.Envelope.MessageId
 	}
 
-	// 简化处理，直接使用主题作为正文预览
-	email.BodyText = email.Subject
+	// 解析正文
+	if body := msg.GetBody(section); body != nil {
+		parsed, err := s.parseMessageBody(body)
+		if err != nil {
+			log.Printf("解析邮件 %s 正文失败: %v", email.MessageID, err)
+		}
+		email.BodyText = parsed.text
+		email.BodyHTML = parsed.html
+		email.Invites = parsed.invites
+	}
 
 	return email
 }
 
-func (s *MCPServer) extractTextBody(entity *message.Entity) string {
-	// 简化实现，返回空字符串
-	return ""
-}
-
 func (s *MCPServer) inferIMAPHost(email string) string {
 	email = strings.ToLower(email)
 
@@ -390,7 +469,7 @@ func (s *MCPServer) inferIMAPHost(email string) string {
 	return ""
 }
 
-func (s *MCPServer) sendError(w http.ResponseWriter, id string, code int, message string) {
+func (s *MCPServer) sendError(w http.ResponseWriter, id json.RawMessage, code int, message string) {
 	resp := MCPResponse{
 		Jsonrpc: "2.0",
 		ID:      id,
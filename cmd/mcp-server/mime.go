@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/charset"
+)
+
+// 单个MIME部分最多读取的字节数，避免超大邮件撑爆内存
+const maxPartBytes = 2 << 20
+
+func init() {
+	// go-message 导入 charset 包后会自动注册字符集转换（GBK/GB2312/Big5/ISO-2022-JP等），
+	// 这里让IMAP信封里的编码主题也使用同一套转换
+	imap.CharsetReader = charset.Reader
+}
+
+// messageBody 邮件正文解析结果
+type messageBody struct {
+	text    string
+	html    string
+	invites []CalendarInvite
+}
+
+// parseMessageBody 解析完整的RFC 822邮件，返回纯文本、HTML正文和日历邀请
+func (s *MCPServer) parseMessageBody(r io.Reader) (messageBody, error) {
+	entity, err := message.Read(r)
+	if err != nil && !isRecoverableMIMEError(err) {
+		return messageBody{}, fmt.Errorf("parse message: %w", err)
+	}
+
+	return s.extractBodies(entity), nil
+}
+
+// extractBodies 遍历多部分邮件树，收集 text/plain、text/html 正文和 text/calendar 邀请。
+// 传输编码（quoted-printable/base64）和字符集由 go-message 自动解码。
+func (s *MCPServer) extractBodies(entity *message.Entity) messageBody {
+	var textParts, htmlParts []string
+	var invites []CalendarInvite
+
+	entity.Walk(func(path []int, part *message.Entity, err error) error {
+		if err != nil && !isRecoverableMIMEError(err) {
+			return nil
+		}
+
+		mediaType, _, _ := part.Header.ContentType()
+		if mediaType == "" && len(path) == 0 {
+			// 没有Content-Type头的邮件按RFC 2045默认为纯文本
+			mediaType = "text/plain"
+		}
+		// 日历邀请常以 invite.ics 附件形式出现，附件也要解析
+		calendar := mediaType == "text/calendar" || mediaType == "application/ics"
+		if isAttachment(part) && !calendar {
+			return nil
+		}
+		if mediaType != "text/plain" && mediaType != "text/html" && !calendar {
+			return nil
+		}
+
+		body, err := io.ReadAll(io.LimitReader(part.Body, maxPartBytes))
+		if err != nil {
+			return nil
+		}
+		content := strings.TrimSpace(string(body))
+		if content == "" {
+			return nil
+		}
+
+		switch {
+		case calendar:
+			invites = append(invites, parseCalendar(content)...)
+		case mediaType == "text/html":
+			htmlParts = append(htmlParts, content)
+		default:
+			textParts = append(textParts, content)
+		}
+		return nil
+	})
+
+	return messageBody{
+		text:    strings.Join(textParts, "\n\n"),
+		html:    strings.Join(htmlParts, "\n"),
+		invites: dedupeInvites(invites),
+	}
+}
+
+// isAttachment 判断MIME部分是否为附件
+func isAttachment(part *message.Entity) bool {
+	disposition, _, err := part.Header.ContentDisposition()
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(disposition, "attachment")
+}
+
+// isRecoverableMIMEError 未知字符集或传输编码时仍可读取原始内容
+func isRecoverableMIMEError(err error) bool {
+	return message.IsUnknownCharset(err) || message.IsUnknownEncoding(err)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// crlf 测试夹具用 LF 书写，转换为邮件的 CRLF
+func crlf(s string) string {
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+func TestParseMessageBodyCharsets(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name: "gbk base64",
+			message: crlf(`Subject: test
+MIME-Version: 1.0
+Content-Type: text/plain; charset=GBK
+Content-Transfer-Encoding: base64
+
+xPq6w6Os0fvH68T6ss6808PmytShow==
+`),
+			want: "您好，邀请您参加面试。",
+		},
+		{
+			name: "gb2312 alias",
+			message: crlf(`Content-Type: text/plain; charset="gb2312"
+Content-Transfer-Encoding: base64
+
+w+bK1NH7x+s=
+`),
+			want: "面试邀请",
+		},
+		{
+			name: "iso-2022-jp 7bit",
+			message: crlf("Content-Type: text/plain; charset=ISO-2022-JP\nContent-Transfer-Encoding: 7bit\n\n" +
+				"\x1b$B0l<!LL@\\$OMh=5$G$9!#\x1b(B\n"),
+			want: "一次面接は来週です。",
+		},
+		{
+			name: "quoted-printable utf-8",
+			message: crlf(`Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Interview on =E5=91=A8=E4=BA=94 at 10:00, see=
+ you then
+`),
+			want: "Interview on 周五 at 10:00, see you then",
+		},
+		{
+			name:    "no content type",
+			message: crlf("Subject: plain\n\nJust text.\n"),
+			want:    "Just text.",
+		},
+		{
+			name:    "unknown charset keeps raw text",
+			message: crlf("Content-Type: text/plain; charset=x-made-up\n\nOffer letter attached\n"),
+			want:    "Offer letter attached",
+		},
+	}
+	s := &MCPServer{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := s.parseMessageBody(strings.NewReader(tt.message))
+			if err != nil {
+				t.Fatalf("parseMessageBody: %v", err)
+			}
+			if body.text != tt.want {
+				t.Errorf("text = %q, want %q", body.text, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMessageBodyNestedMultipart(t *testing.T) {
+	message := crlf(`From: Recruiting <jobs@acme.com>
+Subject: Interview
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/related; boundary="related"
+
+--related
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain; charset=utf-8
+
+Your interview is scheduled.
+--alt
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+<p>Your interview is <b>scheduled</b>.</p>
+--alt
+Content-Type: text/calendar; method=REQUEST; charset=utf-8
+
+BEGIN:VCALENDAR
+METHOD:REQUEST
+BEGIN:VEVENT
+UID:evt-1@acme.com
+SUMMARY:Onsite interview
+DTSTART:20250315T020000Z
+DTEND:20250315T030000Z
+END:VEVENT
+END:VCALENDAR
+--alt--
+--related
+Content-Type: image/png
+Content-ID: <logo>
+Content-Transfer-Encoding: base64
+
+iVBORw0KGgo=
+--related--
+--outer
+Content-Type: text/plain; name="resume.txt"
+Content-Disposition: attachment; filename="resume.txt"
+
+Attached resume text must not become the body.
+--outer
+Content-Type: application/ics; name="invite.ics"
+Content-Disposition: attachment; filename="invite.ics"
+
+BEGIN:VCALENDAR
+METHOD:REQUEST
+BEGIN:VEVENT
+UID:evt-1@acme.com
+DTSTART:20250315T020000Z
+END:VEVENT
+END:VCALENDAR
+--outer--
+`)
+
+	body, err := (&MCPServer{}).parseMessageBody(strings.NewReader(message))
+	if err != nil {
+		t.Fatalf("parseMessageBody: %v", err)
+	}
+	if body.text != "Your interview is scheduled." {
+		t.Errorf("text = %q", body.text)
+	}
+	if body.html != "<p>Your interview is <b>scheduled</b>.</p>" {
+		t.Errorf("html = %q", body.html)
+	}
+	// 内联邀请和 invite.ics 附件是同一个事件
+	if len(body.invites) != 1 || body.invites[0].Summary != "Onsite interview" || body.invites[0].Method != "REQUEST" {
+		t.Errorf("invites = %+v, want the one deduplicated REQUEST", body.invites)
+	}
+}
+
+func TestFetchDecodesEncodedHeaders(t *testing.T) {
+	c, mbox := newTestIMAP(t)
+	messages := []string{
+		"From: =?GBK?B?w+bK1NH7x+s=?= <hr@example.cn>\nSubject: =?GBK?B?w+bK1NH7x+s=?=\nMessage-ID: <gbk@example.cn>\n\nbody\n",
+		"From: =?ISO-2022-JP?B?GyRCOk5NUUM0RXYbKEI=?= <saiyo@example.jp>\nSubject: =?ISO-2022-JP?B?GyRCTExAXCROJDQwRkZiGyhC?=\n" +
+			"Message-ID: <jp@example.jp>\n\nbody\n",
+		"From: jobs@acme.com\nSubject: =?UTF-8?Q?Offer_=E2=80=93_Backend?= =?UTF-8?B?IOW3peeoi+W4iA==?=\nMessage-ID: <utf8@acme.com>\n\nbody\n",
+	}
+	for _, m := range messages {
+		if err := mbox.CreateMessage(nil, time.Now(), strings.NewReader(crlf(m))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Select("INBOX", true); err != nil {
+		t.Fatal(err)
+	}
+
+	emails, err := (&MCPServer{}).fetchUIDs(context.Background(), c, "INBOX", []uint32{7, 8, 9})
+	if err != nil {
+		t.Fatalf("fetchUIDs: %v", err)
+	}
+	want := map[string]string{
+		"<gbk@example.cn>": "面试邀请",
+		"<jp@example.jp>":  "面接のご案内",
+		"<utf8@acme.com>":  "Offer – Backend 工程师",
+	}
+	if len(emails) != len(want) {
+		t.Fatalf("got %d emails, want %d", len(emails), len(want))
+	}
+	for _, e := range emails {
+		if e.Subject != want[e.MessageID] {
+			t.Errorf("%s subject = %q, want %q", e.MessageID, e.Subject, want[e.MessageID])
+		}
+		if e.BodyText != "body" {
+			t.Errorf("%s body = %q", e.MessageID, e.BodyText)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+const (
+	// IMAP XOAUTH2/OAUTHBEARER 需要完整邮箱权限，gmail.readonly 只适用于Gmail API
+	gmailScope         = "https://mail.google.com/"
+	outlookIMAPScope   = "https://outlook.office.com/IMAP.AccessAsUser.All"
+	defaultRedirectURL = "http://localhost:8080/oauth/callback"
+
+	// 登录会话（state）有效期
+	oauthSessionTTL = 10 * time.Minute
+	// 距离过期不足该时长的访问令牌会在抓取前提前刷新
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// newGmailOAuthConfig 从环境变量构建Gmail OAuth配置，未配置客户端ID时返回 nil。
+// GMAIL_AUTH_URL / GMAIL_TOKEN_URL 可指向本地的模拟授权服务器。
+func newGmailOAuthConfig() *oauth2.Config {
+	clientID := os.Getenv("GMAIL_CLIENT_ID")
+	if clientID == "" {
+		return nil
+	}
+
+	endpoint := endpoints.Google
+	if authURL := os.Getenv("GMAIL_AUTH_URL"); authURL != "" {
+		endpoint.AuthURL = authURL
+	}
+	if tokenURL := os.Getenv("GMAIL_TOKEN_URL"); tokenURL != "" {
+		endpoint.TokenURL = tokenURL
+	}
+
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: os.Getenv("GMAIL_CLIENT_SECRET"),
+		Endpoint:     endpoint,
+		RedirectURL:  envOrDefault("GMAIL_REDIRECT_URL", defaultRedirectURL),
+		Scopes:       []string{gmailScope},
+	}
+}
+
+// newOutlookOAuthConfig 从环境变量构建Outlook/Microsoft 365 OAuth配置，未配置客户端ID时返回 nil。
+// OUTLOOK_TENANT 默认为 common，组织账号可填写租户ID。
+func newOutlookOAuthConfig() *oauth2.Config {
+	clientID := os.Getenv("OUTLOOK_CLIENT_ID")
+	if clientID == "" {
+		return nil
+	}
+
+	endpoint := endpoints.AzureAD(os.Getenv("OUTLOOK_TENANT"))
+	if authURL := os.Getenv("OUTLOOK_AUTH_URL"); authURL != "" {
+		endpoint.AuthURL = authURL
+	}
+	if tokenURL := os.Getenv("OUTLOOK_TOKEN_URL"); tokenURL != "" {
+		endpoint.TokenURL = tokenURL
+	}
+
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: os.Getenv("OUTLOOK_CLIENT_SECRET"),
+		Endpoint:     endpoint,
+		RedirectURL:  envOrDefault("OUTLOOK_REDIRECT_URL", defaultRedirectURL),
+		Scopes:       []string{outlookIMAPScope, "offline_access"},
+	}
+}
+
+// newOAuthConfigs 返回已配置的各提供商OAuth配置
+func newOAuthConfigs() map[string]*oauth2.Config {
+	configs := make(map[string]*oauth2.Config)
+	if cfg := newGmailOAuthConfig(); cfg != nil {
+		configs["gmail"] = cfg
+	}
+	if cfg := newOutlookOAuthConfig(); cfg != nil {
+		configs["outlook"] = cfg
+	}
+	return configs
+}
+
+func envOrDefault(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// dataDir 返回本地数据目录，可通过 JOBTRACKER_DATA_DIR 覆盖
+func dataDir() string {
+	if dir := os.Getenv("JOBTRACKER_DATA_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".jobtracker"
+	}
+	return filepath.Join(home, ".jobtracker")
+}
+
+// randomToken 生成URL安全的随机字符串，用于会话ID和state
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// startOAuth 为登录会话生成state和PKCE校验码，返回授权地址
+func (s *MCPServer) startOAuth(session *LoginSession) string {
+	cfg := s.oauthConfigs[session.provider]
+	if cfg == nil {
+		if session.provider == "outlook" {
+			return "https://entra.microsoft.com (请配置OUTLOOK_CLIENT_ID)"
+		}
+		return "https://console.cloud.google.com/apis/credentials (请配置GMAIL_CLIENT_ID)"
+	}
+
+	session.state = randomToken()
+	session.verifier = oauth2.GenerateVerifier()
+
+	return cfg.AuthCodeURL(session.state,
+		oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("prompt", "consent"),
+		oauth2.SetAuthURLParam("login_hint", session.account),
+		oauth2.S256ChallengeOption(session.verifier),
+	)
+}
+
+// takeSessionByState 查找并消费state对应的会话，state只能使用一次
+func (s *MCPServer) takeSessionByState(state string) *LoginSession {
+	if state == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.sessions {
+		if session.state != state {
+			continue
+		}
+		session.state = ""
+		if time.Since(session.createdAt) > oauthSessionTTL {
+			session.Status = "expired"
+			session.Message = "登录会话已过期，请重新登录"
+			return nil
+		}
+		return session
+	}
+	return nil
+}
+
+func (s *MCPServer) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	session := s.takeSessionByState(query.Get("state"))
+	if session == nil {
+		http.Error(w, "无效或已过期的state参数", http.StatusBadRequest)
+		return
+	}
+
+	if errCode := query.Get("error"); errCode != "" {
+		s.failSession(session, fmt.Sprintf("授权被拒绝: %s", errCode))
+		http.Error(w, "授权被拒绝: "+html.EscapeString(errCode), http.StatusForbidden)
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		s.failSession(session, "回调缺少code参数")
+		http.Error(w, "缺少code参数", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	token, err := s.oauthConfigs[session.provider].Exchange(ctx, code, oauth2.VerifierOption(session.verifier))
+	if err != nil {
+		log.Printf("OAuth换取令牌失败: %v", err)
+		s.failSession(session, "换取访问令牌失败")
+		http.Error(w, "换取访问令牌失败", http.StatusBadGateway)
+		return
+	}
+
+	if err := s.saveToken(session.account, token); err != nil {
+		log.Printf("保存令牌失败: %v", err)
+		s.failSession(session, "保存访问令牌失败")
+		http.Error(w, "保存访问令牌失败", http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	session.Status = "completed"
+	session.Message = "OAuth认证完成"
+	session.verifier = ""
+	s.mu.Unlock()
+
+	fmt.Fprintf(w, "<h2>认证完成</h2><p>可以关闭此页面，返回应用程序。</p>")
+}
+
+func (s *MCPServer) failSession(session *LoginSession, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session.Status = "failed"
+	session.Message = message
+	session.verifier = ""
+}
+
+// saveToken 写入加密的磁盘存储，成功后再更新内存缓存
+func (s *MCPServer) saveToken(account string, token *oauth2.Token) error {
+	if err := s.tokenStore.Save(account, token); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[normalizeAccount(account)] = token
+	s.mu.Unlock()
+	return nil
+}
+
+// accessToken 返回账号的有效访问令牌，临近过期时自动用refresh token刷新并持久化
+func (s *MCPServer) accessToken(ctx context.Context, provider, account string) (*oauth2.Token, error) {
+	cfg := s.oauthConfigs[provider]
+	if cfg == nil {
+		return nil, fmt.Errorf("提供商 %s 未配置OAuth客户端", provider)
+	}
+
+	s.mu.Lock()
+	token := s.tokens[normalizeAccount(account)]
+	s.mu.Unlock()
+
+	if token == nil {
+		stored, err := s.tokenStore.Load(account)
+		if err != nil {
+			return nil, err
+		}
+		token = stored
+	}
+	if token == nil {
+		return nil, fmt.Errorf("账号 %s 尚未完成OAuth授权，请先调用 email.login", account)
+	}
+
+	// 内层令牌源只带refresh token，保证外层判断需要刷新时一定会发起刷新
+	refresher := cfg.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken})
+	fresh, err := oauth2.ReuseTokenSourceWithExpiry(token, refresher, tokenRefreshMargin).Token()
+	if err != nil {
+		return nil, fmt.Errorf("刷新访问令牌失败，请重新登录: %w", err)
+	}
+
+	if fresh.AccessToken != token.AccessToken {
+		if err := s.saveToken(account, fresh); err != nil {
+			log.Printf("保存刷新后的令牌失败: %v", err)
+		}
+	}
+
+	return fresh, nil
+}
+
+// hasOAuthToken 判断账号是否已有可用于刷新的令牌
+func (s *MCPServer) hasOAuthToken(account string) bool {
+	s.mu.Lock()
+	token := s.tokens[normalizeAccount(account)]
+	s.mu.Unlock()
+
+	if token == nil {
+		token, _ = s.tokenStore.Load(account)
+	}
+	return token != nil && token.RefreshToken != ""
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const testAccount = "User@Example.com"
+
+// fakeAuthServer 本地模拟的授权服务器，只实现令牌端点
+type fakeAuthServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	challenge string // 授权地址中的 code_challenge
+	exchanges int
+	refreshes int
+}
+
+func newFakeAuthServer(t *testing.T) *fakeAuthServer {
+	t.Helper()
+	f := &fakeAuthServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handleToken))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAuthServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/token" || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		f.exchanges++
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "good-code" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != f.challenge {
+			writeOAuthError(w, "invalid_grant")
+			return
+		}
+		writeToken(w, map[string]interface{}{
+			"access_token":  "access-1",
+			"refresh_token": "refresh-1",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+		})
+
+	case "refresh_token":
+		f.refreshes++
+		if r.PostForm.Get("refresh_token") != "refresh-1" {
+			writeOAuthError(w, "invalid_grant")
+			return
+		}
+		// 刷新响应不带 refresh_token，应沿用旧值
+		writeToken(w, map[string]interface{}{
+			"access_token": "access-2",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+
+	default:
+		writeOAuthError(w, "unsupported_grant_type")
+	}
+}
+
+func writeToken(w http.ResponseWriter, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeOAuthError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func newTestMCPServer(t *testing.T, auth *fakeAuthServer, dir string) *MCPServer {
+	t.Helper()
+	t.Setenv("JOBTRACKER_TOKEN_KEY", "")
+	return &MCPServer{
+		sessions:   make(map[string]*LoginSession),
+		tokens:     make(map[string]*oauth2.Token),
+		tokenStore: NewTokenStore(dir),
+		oauthConfigs: map[string]*oauth2.Config{
+			"gmail": {
+				ClientID:     "client-id",
+				ClientSecret: "client-secret",
+				Endpoint: oauth2.Endpoint{
+					AuthURL:  auth.URL + "/auth",
+					TokenURL: auth.URL + "/token",
+				},
+				RedirectURL: defaultRedirectURL,
+				Scopes:      []string{gmailScope},
+			},
+		},
+	}
+}
+
+// startLogin 发起登录并把授权地址中的 code_challenge 交给模拟服务器，返回会话和state
+func startLogin(t *testing.T, s *MCPServer, auth *fakeAuthServer) (*LoginSession, string) {
+	t.Helper()
+	session, err := s.handleLogin(json.RawMessage(`{"provider":"gmail","email":"` + testAccount + `"}`))
+	if err != nil {
+		t.Fatalf("handleLogin: %v", err)
+	}
+	if session.Status != "pending" {
+		t.Fatalf("session status = %q, want pending", session.Status)
+	}
+
+	loginURL, err := url.Parse(session.LoginURL)
+	if err != nil {
+		t.Fatalf("parse login url: %v", err)
+	}
+	query := loginURL.Query()
+	if got := query.Get("code_challenge_method"); got != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", got)
+	}
+	if got := query.Get("access_type"); got != "offline" {
+		t.Errorf("access_type = %q, want offline", got)
+	}
+	if query.Get("state") == "" || query.Get("code_challenge") == "" {
+		t.Fatalf("login url missing state or code_challenge: %s", session.LoginURL)
+	}
+
+	auth.mu.Lock()
+	auth.challenge = query.Get("code_challenge")
+	auth.mu.Unlock()
+	return session, query.Get("state")
+}
+
+func callback(s *MCPServer, query url.Values) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/oauth/callback?"+query.Encode(), nil)
+	s.handleOAuthCallback(rec, req)
+	return rec
+}
+
+func TestOAuthCodeExchange(t *testing.T) {
+	auth := newFakeAuthServer(t)
+	dir := t.TempDir()
+	s := newTestMCPServer(t, auth, dir)
+	session, state := startLogin(t, s, auth)
+
+	rec := callback(s, url.Values{"state": {state}, "code": {"good-code"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if session.Status != "completed" {
+		t.Errorf("session status = %q, want completed", session.Status)
+	}
+	if session.verifier != "" {
+		t.Error("verifier not cleared after exchange")
+	}
+
+	// 令牌应持久化到加密存储，重新打开后仍可读取
+	token, err := NewTokenStore(dir).Load(testAccount)
+	if err != nil {
+		t.Fatalf("load token: %v", err)
+	}
+	if token == nil || token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" {
+		t.Fatalf("stored token = %+v", token)
+	}
+	if !s.hasOAuthToken(testAccount) {
+		t.Error("hasOAuthToken = false after login")
+	}
+
+	// state 只能使用一次
+	rec = callback(s, url.Values{"state": {state}, "code": {"good-code"}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("reused state: status = %d, want 400", rec.Code)
+	}
+	if auth.exchanges != 1 {
+		t.Errorf("exchanges = %d, want 1", auth.exchanges)
+	}
+}
+
+func TestOAuthCallbackRejectsBadState(t *testing.T) {
+	auth := newFakeAuthServer(t)
+	s := newTestMCPServer(t, auth, t.TempDir())
+	session, state := startLogin(t, s, auth)
+
+	for _, bad := range []string{"", "not-the-state"} {
+		rec := callback(s, url.Values{"state": {bad}, "code": {"good-code"}})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("state %q: status = %d, want 400", bad, rec.Code)
+		}
+	}
+	if session.Status != "pending" {
+		t.Errorf("session status = %q, want pending", session.Status)
+	}
+
+	// 过期的会话
+	session.createdAt = time.Now().Add(-oauthSessionTTL - time.Minute)
+	rec := callback(s, url.Values{"state": {state}, "code": {"good-code"}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expired session: status = %d, want 400", rec.Code)
+	}
+	if session.Status != "expired" {
+		t.Errorf("session status = %q, want expired", session.Status)
+	}
+	if auth.exchanges != 0 {
+		t.Errorf("exchanges = %d, want 0", auth.exchanges)
+	}
+}
+
+func TestOAuthCallbackFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  url.Values
+		status int
+	}{
+		{"denied", url.Values{"error": {"access_denied"}}, http.StatusForbidden},
+		{"missing code", url.Values{}, http.StatusBadRequest},
+		{"bad code", url.Values{"code": {"wrong-code"}}, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newFakeAuthServer(t)
+			s := newTestMCPServer(t, auth, t.TempDir())
+			session, state := startLogin(t, s, auth)
+
+			tt.query.Set("state", state)
+			rec := callback(s, tt.query)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if session.Status != "failed" {
+				t.Errorf("session status = %q, want failed", session.Status)
+			}
+			if s.hasOAuthToken(testAccount) {
+				t.Error("token saved after failed login")
+			}
+		})
+	}
+}
+
+func TestOAuthCallbackWrongVerifier(t *testing.T) {
+	auth := newFakeAuthServer(t)
+	s := newTestMCPServer(t, auth, t.TempDir())
+	session, state := startLogin(t, s, auth)
+
+	// 换取令牌时携带的校验码必须与授权地址中的 code_challenge 对应
+	session.verifier = oauth2.GenerateVerifier()
+	rec := callback(s, url.Values{"state": {state}, "code": {"good-code"}})
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+	if session.Status != "failed" {
+		t.Errorf("session status = %q, want failed", session.Status)
+	}
+}
+
+func TestOAuthCallbackSaveFailure(t *testing.T) {
+	auth := newFakeAuthServer(t)
+
+	// 令牌目录是一个普通文件，保存必然失败
+	dir := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(dir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestMCPServer(t, auth, dir)
+	session, state := startLogin(t, s, auth)
+
+	rec := callback(s, url.Values{"state": {state}, "code": {"good-code"}})
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if session.Status != "failed" {
+		t.Errorf("session status = %q, want failed", session.Status)
+	}
+	if s.tokens[normalizeAccount(testAccount)] != nil {
+		t.Error("token cached in memory although saving failed")
+	}
+}
+
+func TestAccessTokenRefresh(t *testing.T) {
+	auth := newFakeAuthServer(t)
+	dir := t.TempDir()
+	s := newTestMCPServer(t, auth, dir)
+
+	expired := &oauth2.Token{
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Minute), // 在提前刷新的窗口内
+	}
+	if err := s.tokenStore.Save(testAccount, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := s.accessToken(context.Background(), "gmail", testAccount)
+	if err != nil {
+		t.Fatalf("accessToken: %v", err)
+	}
+	if token.AccessToken != "access-2" {
+		t.Errorf("access token = %q, want refreshed access-2", token.AccessToken)
+	}
+	if auth.refreshes != 1 {
+		t.Errorf("refreshes = %d, want 1", auth.refreshes)
+	}
+
+	// 刷新后的令牌已持久化，且保留了原来的 refresh token
+	stored, err := NewTokenStore(dir).Load(testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.AccessToken != "access-2" || stored.RefreshToken != "refresh-1" {
+		t.Errorf("stored token = %+v", stored)
+	}
+
+	// 未临近过期时不再刷新
+	if _, err := s.accessToken(context.Background(), "gmail", testAccount); err != nil {
+		t.Fatal(err)
+	}
+	if auth.refreshes != 1 {
+		t.Errorf("refreshes = %d after valid token, want 1", auth.refreshes)
+	}
+}
+
+func TestAccessTokenRefreshRejected(t *testing.T) {
+	auth := newFakeAuthServer(t)
+	s := newTestMCPServer(t, auth, t.TempDir())
+
+	revoked := &oauth2.Token{AccessToken: "old", RefreshToken: "revoked", Expiry: time.Now().Add(-time.Hour)}
+	if err := s.tokenStore.Save(testAccount, revoked); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.accessToken(context.Background(), "gmail", testAccount); err == nil {
+		t.Error("accessToken succeeded with revoked refresh token")
+	}
+	if _, err := s.accessToken(context.Background(), "gmail", "nobody@example.com"); err == nil {
+		t.Error("accessToken succeeded for account without token")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 单页最多返回的邮件数量，max_emails 更大时需要通过 page_token 翻页
+const maxPageSize = 200
+
+// errPageTokenExpired 文件夹的 UIDVALIDITY 在翻页过程中发生了变化
+var errPageTokenExpired = errors.New("page_token 已失效（文件夹UIDVALIDITY已变化），请重新从第一页开始")
+
+// FetchResult 一页抓取结果，邮件按文件夹顺序、同一文件夹内从新到旧排列
+type FetchResult struct {
+	Emails []Email `json:"emails"`
+	// 还有更多邮件时返回，原样传回 page_token 获取下一页
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// pageToken 翻页位置，对客户端不透明（base64编码的JSON）
+type pageToken struct {
+	// 查询条件指纹，防止把令牌用于不同的查询
+	Query string `json:"q"`
+	// 下一页从 Folders[Folder] 开始
+	Folder      int    `json:"f"`
+	UIDValidity uint32 `json:"v,omitempty"`
+	// 只返回UID小于该值的邮件，0 表示从最新的邮件开始
+	BeforeUID uint32 `json:"b,omitempty"`
+}
+
+func encodePageToken(token *pageToken) string {
+	return base64.RawURLEncoding.EncodeToString(mustMarshal(token))
+}
+
+func decodePageToken(s string, params FetchParams) (*pageToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page_token")
+	}
+	var token pageToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("invalid page_token")
+	}
+	if token.Query != queryFingerprint(params) {
+		return nil, fmt.Errorf("page_token does not match the query")
+	}
+	if token.Folder < 0 || token.Folder >= len(params.Folders) {
+		return nil, fmt.Errorf("invalid page_token")
+	}
+	return &token, nil
+}
+
+// queryFingerprint 由账号、时间范围、文件夹和筛选条件计算查询指纹；max_emails 可以逐页变化
+func queryFingerprint(params FetchParams) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s\n%s\n%s",
+		normalizeAccount(params.Email),
+		params.StartDate.UTC().Format(time.RFC3339),
+		params.EndDate.UTC().Format(time.RFC3339),
+		strings.Join(params.Folders, "\x00"),
+		strings.Join(params.Keywords, "\x00"),
+		strings.Join(params.SenderDomains, "\x00"),
+		strings.Join(params.SubjectPatterns, "\x00"))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
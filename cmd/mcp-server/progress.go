@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// MCPNotification 服务器发给客户端的通知（没有ID）
+type MCPNotification struct {
+	Jsonrpc string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// notifyFunc 把通知写回发起请求的连接（SSE流或stdio）
+type notifyFunc func(notification *MCPNotification)
+
+type ctxKey int
+
+const (
+	notifierKey ctxKey = iota
+	progressKey
+	sessionKey
+)
+
+// withNotifier 为请求上下文绑定通知发送函数，由各传输方式设置
+func withNotifier(ctx context.Context, notify notifyFunc) context.Context {
+	return context.WithValue(ctx, notifierKey, notify)
+}
+
+// withSession 为请求上下文绑定传输层会话ID（HTTP的 Mcp-Session-Id），
+// 不同会话的JSON-RPC请求ID可能相同。stdio 只有一个会话，不需要设置
+func withSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionKey, sessionID)
+}
+
+func sessionFrom(ctx context.Context) string {
+	id, _ := ctx.Value(sessionKey).(string)
+	return id
+}
+
+// progressTracker 记录单个请求的进度令牌，保证 progress 值单调递增
+type progressTracker struct {
+	mu     sync.Mutex
+	token  json.RawMessage
+	step   float64
+	notify notifyFunc
+}
+
+// withProgress 请求参数携带 _meta.progressToken 且传输支持通知时启用进度上报
+func withProgress(ctx context.Context, params json.RawMessage) context.Context {
+	notify, ok := ctx.Value(notifierKey).(notifyFunc)
+	if !ok || len(params) == 0 {
+		return ctx
+	}
+
+	var meta struct {
+		Meta struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(params, &meta); err != nil || len(meta.Meta.ProgressToken) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, progressKey, &progressTracker{
+		token:  meta.Meta.ProgressToken,
+		notify: notify,
+	})
+}
+
+// reportProgress 发送 notifications/progress，total 未知时传 0。
+// 请求没有进度令牌时不做任何事。
+func reportProgress(ctx context.Context, total float64, message string) {
+	tracker, ok := ctx.Value(progressKey).(*progressTracker)
+	if !ok {
+		return
+	}
+
+	tracker.mu.Lock()
+	tracker.step++
+	params := map[string]interface{}{
+		"progressToken": tracker.token,
+		"progress":      tracker.step,
+		"message":       message,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	tracker.mu.Unlock()
+
+	tracker.notify(&MCPNotification{
+		Jsonrpc: "2.0",
+		Method:  "notifications/progress",
+		Params:  params,
+	})
+}
+
+// inflightKey 进行中请求的键：JSON-RPC请求ID只在会话内唯一
+type inflightKey struct {
+	session string
+	id      string
+}
+
+// inflightRequest 用指针区分同一键下先后登记的请求
+type inflightRequest struct {
+	cancel context.CancelFunc
+}
+
+// trackRequest 登记进行中的请求，同一会话收到 notifications/cancelled 时取消其上下文
+func (s *MCPServer) trackRequest(ctx context.Context, id json.RawMessage) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := inflightKey{session: sessionFrom(ctx), id: string(id)}
+	req := &inflightRequest{cancel: cancel}
+
+	s.mcp.mu.Lock()
+	if s.mcp.inflight == nil {
+		s.mcp.inflight = make(map[inflightKey]*inflightRequest)
+	}
+	s.mcp.inflight[key] = req
+	s.mcp.mu.Unlock()
+
+	return ctx, func() {
+		s.mcp.mu.Lock()
+		// 只删除自己登记的条目
+		if s.mcp.inflight[key] == req {
+			delete(s.mcp.inflight, key)
+		}
+		s.mcp.mu.Unlock()
+		cancel()
+	}
+}
+
+// handleCancelled 处理客户端发来的 notifications/cancelled，只取消同一会话中的请求
+func (s *MCPServer) handleCancelled(session string, params json.RawMessage) {
+	var cancelled struct {
+		RequestID json.RawMessage `json:"requestId"`
+		Reason    string          `json:"reason"`
+	}
+	if err := json.Unmarshal(params, &cancelled); err != nil {
+		return
+	}
+
+	s.mcp.mu.Lock()
+	req := s.mcp.inflight[inflightKey{session: session, id: string(cancelled.RequestID)}]
+	s.mcp.mu.Unlock()
+
+	if req != nil {
+		req.cancel()
+	}
+}
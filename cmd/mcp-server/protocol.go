@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+)
+
+const (
+	serverName    = "jobtracker-mail"
+	serverVersion = "0.2.0"
+)
+
+// 支持的MCP协议版本，第一个为最新版本
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC 错误码
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeServerError    = -32000
+)
+
+// rpcError 携带JSON-RPC错误码的错误
+type rpcError struct {
+	code    int
+	message string
+}
+
+func (e *rpcError) Error() string { return e.message }
+
+func invalidParams(message string) error {
+	return &rpcError{code: codeInvalidParams, message: message}
+}
+
+// initialize 请求参数
+type InitializeParams struct {
+	ProtocolVersion string          `json:"protocolVersion"`
+	Capabilities    json.RawMessage `json:"capabilities"`
+	ClientInfo      Implementation  `json:"clientInfo"`
+}
+
+type InitializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
+}
+
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type ServerCapabilities struct {
+	Tools *ToolsCapability `json:"tools,omitempty"`
+}
+
+type ToolsCapability struct {
+	ListChanged bool `json:"listChanged"`
+}
+
+// mcpState 记录协议握手状态
+type mcpState struct {
+	mu              sync.Mutex
+	protocolVersion string
+	clientInfo      Implementation
+	initialized     bool
+	inflight        map[inflightKey]*inflightRequest
+	httpSessions    map[string]bool
+}
+
+// dispatch 处理一条JSON-RPC消息，通知消息返回 nil。
+// 所有传输方式（HTTP、stdio）共用这一入口。
+func (s *MCPServer) dispatch(ctx context.Context, req *MCPRequest) *MCPResponse {
+	isNotification := len(req.ID) == 0
+
+	if req.Jsonrpc != "2.0" || req.Method == "" {
+		if isNotification {
+			return nil
+		}
+		return errorResponse(req.ID, codeInvalidRequest, "Invalid Request")
+	}
+
+	if !isNotification {
+		var done func()
+		ctx, done = s.trackRequest(ctx, req.ID)
+		defer done()
+		ctx = withProgress(ctx, req.Params)
+	}
+
+	var result interface{}
+	var err error
+
+	switch req.Method {
+	case "initialize":
+		result, err = s.handleInitialize(req.Params)
+	case "notifications/initialized":
+		s.mcp.mu.Lock()
+		s.mcp.initialized = true
+		s.mcp.mu.Unlock()
+		return nil
+	case "notifications/cancelled":
+		s.handleCancelled(sessionFrom(ctx), req.Params)
+		return nil
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = ListToolsResult{Tools: s.tools()}
+	case "tools/call":
+		result, err = s.handleToolsCall(ctx, req.Params)
+
+	// 兼容旧版客户端（internal/client.MCPEmailClient）的自定义方法
+	case "email.login":
+		result, err = s.handleLogin(req.Params)
+	case "email.fetch":
+		result, err = s.handleFetch(ctx, req.Params)
+	case "email.sync":
+		result, err = s.handleSync(ctx, req.Params)
+
+	default:
+		if isNotification {
+			// 未知通知直接忽略
+			return nil
+		}
+		return errorResponse(req.ID, codeMethodNotFound, "Method not found")
+	}
+
+	if isNotification {
+		return nil
+	}
+
+	if err != nil {
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			return errorResponse(req.ID, rpcErr.code, rpcErr.message)
+		}
+		return errorResponse(req.ID, codeServerError, err.Error())
+	}
+
+	return &MCPResponse{
+		Jsonrpc: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// handleInitialize 协商协议版本并返回服务器能力
+func (s *MCPServer) handleInitialize(params json.RawMessage) (*InitializeResult, error) {
+	var initParams InitializeParams
+	if err := json.Unmarshal(params, &initParams); err != nil {
+		return nil, invalidParams("invalid initialize parameters")
+	}
+
+	// 客户端请求的版本受支持时使用该版本，否则返回服务器支持的最新版本
+	version := supportedProtocolVersions[0]
+	for _, v := range supportedProtocolVersions {
+		if v == initParams.ProtocolVersion {
+			version = v
+			break
+		}
+	}
+
+	s.mcp.mu.Lock()
+	s.mcp.protocolVersion = version
+	s.mcp.clientInfo = initParams.ClientInfo
+	s.mcp.initialized = false
+	s.mcp.mu.Unlock()
+
+	log.Printf("MCP客户端连接: %s %s (协议版本 %s)",
+		initParams.ClientInfo.Name, initParams.ClientInfo.Version, version)
+
+	return &InitializeResult{
+		ProtocolVersion: version,
+		Capabilities: ServerCapabilities{
+			Tools: &ToolsCapability{ListChanged: false},
+		},
+		ServerInfo: Implementation{
+			Name:    serverName,
+			Version: serverVersion,
+		},
+		Instructions: "邮箱读取服务：先调用 email_login 完成授权，再用 email_fetch 按时间范围抓取邮件，或用 email_search 按关键词搜索；email_sync 只返回上次同步之后的新邮件。",
+	}, nil
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *MCPResponse {
+	return &MCPResponse{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Error: &MCPError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCancelIsScopedToSession(t *testing.T) {
+	s := &MCPServer{}
+	id := json.RawMessage(`1`)
+
+	ctxA, doneA := s.trackRequest(withSession(context.Background(), "session-a"), id)
+	defer doneA()
+	ctxB, doneB := s.trackRequest(withSession(context.Background(), "session-b"), id)
+
+	s.handleCancelled("session-b", json.RawMessage(`{"requestId":1}`))
+	if ctxB.Err() == nil {
+		t.Error("request in session-b not cancelled")
+	}
+	if ctxA.Err() != nil {
+		t.Fatal("cancelling session-b's request cancelled session-a's request with the same id")
+	}
+
+	// session-b 的请求结束不能删除 session-a 的登记
+	doneB()
+	s.handleCancelled("session-a", json.RawMessage(`{"requestId":1}`))
+	if ctxA.Err() == nil {
+		t.Error("request in session-a not cancelled after session-b finished")
+	}
+}
+
+func TestHTTPInitializeSession(t *testing.T) {
+	s := &MCPServer{}
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		rec := httptest.NewRecorder()
+		s.handleMCP(rec, req)
+		return rec
+	}
+
+	failed := post(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":"not an object"}`)
+	if id := failed.Header().Get("Mcp-Session-Id"); id != "" {
+		t.Errorf("failed initialize issued session %q", id)
+	}
+	var resp MCPResponse
+	if err := json.Unmarshal(failed.Body.Bytes(), &resp); err != nil || resp.Error == nil {
+		t.Errorf("failed initialize response = %s, want a JSON-RPC error", failed.Body)
+	}
+	if n := len(s.mcp.httpSessions); n != 0 {
+		t.Errorf("%d sessions registered after failed initialize", n)
+	}
+
+	ok := post(`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"test","version":"1"}}}`)
+	id := ok.Header().Get("Mcp-Session-Id")
+	if id == "" || !s.hasHTTPSession(id) {
+		t.Fatalf("successful initialize session = %q", id)
+	}
+	resp = MCPResponse{}
+	if err := json.Unmarshal(ok.Body.Bytes(), &resp); err != nil || resp.Error != nil {
+		t.Errorf("initialize response = %s", ok.Body)
+	}
+}
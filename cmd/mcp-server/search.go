@@ -0,0 +1,184 @@
+package main
+
+import (
+	"log"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/commands"
+	"github.com/emersion/go-imap/responses"
+)
+
+// searchFilter 邮件筛选条件，各条件之间是“或”的关系，全部为空时不筛选
+type searchFilter struct {
+	keywords []string // 匹配主题、发件人和正文
+	domains  []string // 发件人域名
+	subjects []string // 主题片段
+}
+
+func newSearchFilter(params FetchParams) searchFilter {
+	return searchFilter{
+		keywords: nonEmpty(params.Keywords),
+		domains:  nonEmpty(params.SenderDomains),
+		subjects: nonEmpty(params.SubjectPatterns),
+	}
+}
+
+func (f searchFilter) empty() bool {
+	return len(f.keywords) == 0 && len(f.domains) == 0 && len(f.subjects) == 0
+}
+
+// match 本地判断邮件是否满足筛选条件（不区分大小写），用于服务器搜索不可用时
+func (f searchFilter) match(email Email) bool {
+	if f.empty() {
+		return true
+	}
+
+	from := strings.ToLower(email.From)
+	for _, domain := range f.domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "@"))
+		if strings.HasSuffix(from, "@"+domain) || strings.HasSuffix(from, "."+domain) {
+			return true
+		}
+	}
+
+	subject := strings.ToLower(email.Subject)
+	for _, pattern := range f.subjects {
+		if strings.Contains(subject, strings.ToLower(pattern)) {
+			return true
+		}
+	}
+
+	return containsKeyword(email, f.keywords)
+}
+
+// criteria 把筛选条件转换为 OR 连接的 TEXT/FROM/SUBJECT 搜索键
+func (f searchFilter) criteria() *imap.SearchCriteria {
+	var keys []*imap.SearchCriteria
+	for _, keyword := range f.keywords {
+		keys = append(keys, &imap.SearchCriteria{Text: []string{keyword}})
+	}
+	for _, domain := range f.domains {
+		keys = append(keys, &imap.SearchCriteria{Header: textproto.MIMEHeader{"From": {strings.TrimPrefix(domain, "@")}}})
+	}
+	for _, pattern := range f.subjects {
+		keys = append(keys, &imap.SearchCriteria{Header: textproto.MIMEHeader{"Subject": {pattern}}})
+	}
+	return orCriteria(keys)
+}
+
+// orCriteria 把多个搜索键组合成平衡的 OR 树，避免嵌套过深
+func orCriteria(keys []*imap.SearchCriteria) *imap.SearchCriteria {
+	if len(keys) == 1 {
+		return keys[0]
+	}
+	mid := len(keys) / 2
+	return &imap.SearchCriteria{
+		Or: [][2]*imap.SearchCriteria{{orCriteria(keys[:mid]), orCriteria(keys[mid:])}},
+	}
+}
+
+// gmailRaw 把筛选条件转换为Gmail搜索语法，花括号内的条件任一匹配即可
+func (f searchFilter) gmailRaw() string {
+	var terms []string
+	for _, keyword := range f.keywords {
+		terms = append(terms, gmailQuote(keyword))
+	}
+	for _, domain := range f.domains {
+		terms = append(terms, "from:"+gmailQuote(strings.TrimPrefix(domain, "@")))
+	}
+	for _, pattern := range f.subjects {
+		terms = append(terms, "subject:"+gmailQuote(pattern))
+	}
+	return "{" + strings.Join(terms, " ") + "}"
+}
+
+func gmailQuote(term string) string {
+	if strings.ContainsAny(term, " \t\"{}()") {
+		return `"` + strings.ReplaceAll(term, `"`, "") + `"`
+	}
+	return term
+}
+
+// gmailRawSearch 带 X-GM-RAW 搜索键的 SEARCH 命令（Gmail IMAP扩展）
+type gmailRawSearch struct {
+	criteria *imap.SearchCriteria
+	raw      string
+}
+
+func (cmd *gmailRawSearch) Command() *imap.Command {
+	args := []interface{}{imap.RawString("CHARSET"), imap.RawString("UTF-8")}
+	args = append(args, cmd.criteria.Format()...)
+	args = append(args, imap.RawString("X-GM-RAW"), cmd.raw)
+	return &imap.Command{Name: "SEARCH", Arguments: args}
+}
+
+// searchUIDs 在当前文件夹中搜索符合时间范围和筛选条件的邮件UID。
+// 服务器无法执行关键词搜索（如不支持所用字符集）时，只按时间范围搜索，
+// 并返回 postFilter=true，由调用方在本地过滤。
+func (s *MCPServer) searchUIDs(c *client.Client, base *imap.SearchCriteria, filter searchFilter) (uids []uint32, postFilter bool, err error) {
+	if filter.empty() {
+		uids, err = c.UidSearch(base)
+		return uids, false, err
+	}
+
+	if ok, _ := c.Support("X-GM-EXT-1"); ok {
+		uids, err = gmailSearch(c, base, filter.gmailRaw())
+		if err == nil {
+			return uids, false, nil
+		}
+		log.Printf("X-GM-RAW 搜索失败，改用标准搜索: %v", err)
+	}
+
+	// 与时间范围是“与”的关系：筛选条件只有一个键时直接合并，否则合并其 OR 节点
+	criteria := *base
+	key := filter.criteria()
+	criteria.Or = append(criteria.Or, key.Or...)
+	criteria.Text = append(criteria.Text, key.Text...)
+	criteria.Header = key.Header
+	if uids, err = c.UidSearch(&criteria); err == nil {
+		return uids, false, nil
+	}
+	log.Printf("服务器关键词搜索失败，改为本地过滤: %v", err)
+
+	uids, err = c.UidSearch(base)
+	return uids, true, err
+}
+
+func gmailSearch(c *client.Client, base *imap.SearchCriteria, raw string) ([]uint32, error) {
+	res := new(responses.Search)
+	status, err := c.Execute(&commands.Uid{Cmd: &gmailRawSearch{criteria: base, raw: raw}}, res)
+	if err != nil {
+		return nil, err
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+	return res.Ids, nil
+}
+
+// baseCriteria 按时间范围（包含结束日期）和UID上限构建搜索条件
+func baseCriteria(start, end time.Time, before uint32) *imap.SearchCriteria {
+	criteria := imap.NewSearchCriteria()
+	criteria.Since = start
+	criteria.Before = end.AddDate(0, 0, 1)
+	if before > 1 {
+		uidRange := new(imap.SeqSet)
+		uidRange.AddRange(1, before-1)
+		criteria.Uid = uidRange
+	}
+	return criteria
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// 增量同步参数
+type SyncParams struct {
+	Provider string   `json:"provider"`
+	Email    string   `json:"email"`
+	Folders  []string `json:"folders"`
+	// 首次同步或 UIDVALIDITY 变化需要全量同步时，从该日期开始
+	StartDate time.Time `json:"start_date"`
+	MaxEmails int       `json:"max_emails"`
+	// 上次同步返回的游标；为空时使用服务器保存的检查点
+	Cursor string `json:"cursor"`
+}
+
+type SyncResult struct {
+	Emails []Email `json:"emails"`
+	Cursor string  `json:"cursor"`
+	// 新邮件超过 MaxEmails 时为 true，可以用返回的游标继续同步
+	HasMore bool `json:"has_more"`
+	// 因首次同步或 UIDVALIDITY 变化而全量同步的文件夹
+	Resynced []string `json:"resynced,omitempty"`
+}
+
+// folderCheckpoint 单个文件夹的同步检查点
+type folderCheckpoint struct {
+	UIDValidity uint32 `json:"uid_validity"`
+	LastUID     uint32 `json:"last_uid"`
+}
+
+// syncCursor 游标内容，对客户端不透明（base64编码的JSON）
+type syncCursor struct {
+	Account string                      `json:"account"`
+	Folders map[string]folderCheckpoint `json:"folders"`
+}
+
+func encodeCursor(cursor *syncCursor) string {
+	return base64.RawURLEncoding.EncodeToString(mustMarshal(cursor))
+}
+
+func decodeCursor(s string) (*syncCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor syncCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if cursor.Folders == nil {
+		cursor.Folders = make(map[string]folderCheckpoint)
+	}
+	return &cursor, nil
+}
+
+// CheckpointStore 按账号、文件夹持久化同步检查点
+type CheckpointStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewCheckpointStore(dir string) *CheckpointStore {
+	return &CheckpointStore{path: filepath.Join(dir, "sync_state.json")}
+}
+
+// Load 返回账号的所有文件夹检查点
+func (cs *CheckpointStore) Load(account string) (map[string]folderCheckpoint, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	state, err := cs.read()
+	if err != nil {
+		return nil, err
+	}
+	folders := state[normalizeAccount(account)]
+	if folders == nil {
+		folders = make(map[string]folderCheckpoint)
+	}
+	return folders, nil
+}
+
+// Save 覆盖账号中出现在 folders 里的文件夹检查点
+func (cs *CheckpointStore) Save(account string, folders map[string]folderCheckpoint) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	state, err := cs.read()
+	if err != nil {
+		return err
+	}
+
+	key := normalizeAccount(account)
+	if state[key] == nil {
+		state[key] = make(map[string]folderCheckpoint)
+	}
+	for folder, checkpoint := range folders {
+		state[key][folder] = checkpoint
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal sync state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cs.path), 0o700); err != nil {
+		return fmt.Errorf("create data dir: %w", err)
+	}
+
+	tmp := cs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write sync state: %w", err)
+	}
+	return os.Rename(tmp, cs.path)
+}
+
+func (cs *CheckpointStore) read() (map[string]map[string]folderCheckpoint, error) {
+	state := make(map[string]map[string]folderCheckpoint)
+
+	data, err := os.ReadFile(cs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read sync state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse sync state: %w", err)
+	}
+	return state, nil
+}
+
+func (s *MCPServer) handleSync(ctx context.Context, params json.RawMessage) (*SyncResult, error) {
+	var syncParams SyncParams
+	if err := json.Unmarshal(params, &syncParams); err != nil {
+		return nil, fmt.Errorf("invalid sync parameters")
+	}
+
+	return s.syncEmails(ctx, syncParams)
+}
+
+// syncEmails 只抓取上次同步之后的新邮件。
+// 文件夹的 UIDVALIDITY 变化时，旧的UID全部失效，回退为按日期全量同步。
+func (s *MCPServer) syncEmails(ctx context.Context, params SyncParams) (*SyncResult, error) {
+	if len(params.Folders) == 0 {
+		params.Folders = []string{"INBOX"}
+	}
+	if params.MaxEmails <= 0 {
+		params.MaxEmails = 100
+	}
+
+	checkpoints, err := s.loadCheckpoints(params)
+	if err != nil {
+		return nil, err
+	}
+
+	host := s.imapHost(params.Provider, params.Email)
+	c, closeFn, err := s.connectIMAP(ctx, params.Provider, params.Email, host)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	result := &SyncResult{Emails: []Email{}}
+	updated := make(map[string]folderCheckpoint)
+
+	for i, folder := range params.Folders {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reportProgress(ctx, 0, fmt.Sprintf("正在同步文件夹 %s (%d/%d)", folder, i+1, len(params.Folders)))
+
+		remaining := params.MaxEmails - len(result.Emails)
+		if remaining <= 0 {
+			result.HasMore = true
+			break
+		}
+
+		synced, err := s.syncFolder(ctx, c, folder, checkpoints[folder], params.StartDate, remaining)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("同步文件夹 %s 失败: %v", folder, err)
+			continue
+		}
+
+		result.Emails = append(result.Emails, synced.emails...)
+		result.HasMore = result.HasMore || synced.hasMore
+		if synced.resynced {
+			result.Resynced = append(result.Resynced, folder)
+		}
+		checkpoints[folder] = synced.checkpoint
+		updated[folder] = synced.checkpoint
+	}
+
+	result.Cursor = encodeCursor(&syncCursor{Account: normalizeAccount(params.Email), Folders: checkpoints})
+
+	if err := s.checkpoints.Save(params.Email, updated); err != nil {
+		log.Printf("保存同步检查点失败: %v", err)
+	}
+
+	return result, nil
+}
+
+// loadCheckpoints 优先使用客户端传来的游标，否则读取服务器保存的检查点
+func (s *MCPServer) loadCheckpoints(params SyncParams) (map[string]folderCheckpoint, error) {
+	if params.Cursor == "" {
+		return s.checkpoints.Load(params.Email)
+	}
+
+	cursor, err := decodeCursor(params.Cursor)
+	if err != nil {
+		return nil, invalidParams(err.Error())
+	}
+	if cursor.Account != normalizeAccount(params.Email) {
+		return nil, invalidParams("cursor belongs to a different account")
+	}
+	return cursor.Folders, nil
+}
+
+// folderSync 单个文件夹的同步结果
+type folderSync struct {
+	emails     []Email
+	checkpoint folderCheckpoint
+	resynced   bool
+	hasMore    bool
+}
+
+// syncFolder 同步单个文件夹，返回新邮件（按UID升序）和新的检查点
+func (s *MCPServer) syncFolder(ctx context.Context, c *client.Client, folder string, checkpoint folderCheckpoint, since time.Time, limit int) (*folderSync, error) {
+	mbox, err := c.Select(folder, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &folderSync{checkpoint: checkpoint}
+
+	result.resynced = checkpoint.UIDValidity == 0 || checkpoint.UIDValidity != mbox.UidValidity
+	if result.resynced {
+		if checkpoint.UIDValidity != 0 {
+			log.Printf("文件夹 %s 的UIDVALIDITY已变化 (%d -> %d)，执行全量同步", folder, checkpoint.UIDValidity, mbox.UidValidity)
+		}
+		result.checkpoint = folderCheckpoint{UIDValidity: mbox.UidValidity}
+	} else if mbox.UidNext != 0 && mbox.UidNext <= checkpoint.LastUID+1 {
+		// UIDNEXT 没有前进，说明没有新邮件，无需搜索
+		return result, nil
+	}
+
+	criteria := imap.NewSearchCriteria()
+	if result.resynced {
+		if !since.IsZero() {
+			criteria.Since = since
+		}
+	} else {
+		uidRange := new(imap.SeqSet)
+		uidRange.AddRange(checkpoint.LastUID+1, 0) // 0 表示 *
+		criteria.Uid = uidRange
+	}
+
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	// "N:*" 在没有更大UID时也会返回最后一封邮件，需要过滤
+	var fresh []uint32
+	for _, uid := range uids {
+		if uid > result.checkpoint.LastUID {
+			fresh = append(fresh, uid)
+		}
+	}
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i] < fresh[j] })
+
+	// 按UID从小到大推进检查点，超出上限的部分留给下次同步
+	if len(fresh) > limit {
+		fresh = fresh[:limit]
+		result.hasMore = true
+	}
+	if len(fresh) == 0 {
+		if result.resynced && mbox.UidNext > 0 {
+			// 全量同步没有找到 start_date 之后的邮件：检查点推进到当前最大UID，
+			// 否则 LastUID 为 0，下次同步会不带日期条件搜索 1:*，拉取整个旧邮箱
+			result.checkpoint.LastUID = mbox.UidNext - 1
+		}
+		return result, nil
+	}
+
+	result.emails, err = s.fetchUIDs(ctx, c, folder, fresh)
+	if err != nil {
+		return nil, err
+	}
+
+	result.checkpoint.LastUID = fresh[len(fresh)-1]
+	return result, nil
+}
+
+// fetchUIDs 按UID分批获取邮件（信封 + 完整正文），每批结束后上报进度
+func (s *MCPServer) fetchUIDs(ctx context.Context, c *client.Client, folder string, uids []uint32) ([]Email, error) {
+	// 获取完整的RFC 822内容（PEEK不会把邮件标记为已读）
+	section := &imap.BodySectionName{Peek: true}
+	items := []imap.FetchItem{imap.FetchUid, imap.FetchEnvelope, section.FetchItem()}
+
+	var emails []Email
+	for start := 0; start < len(uids); start += fetchBatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		end := start + fetchBatchSize
+		if end > len(uids) {
+			end = len(uids)
+		}
+
+		uidset := new(imap.SeqSet)
+		uidset.AddNum(uids[start:end]...)
+
+		messages := make(chan *imap.Message, end-start)
+		done := make(chan error, 1)
+
+		go func() {
+			done <- c.UidFetch(uidset, items, messages)
+		}()
+
+		for msg := range messages {
+			emails = append(emails, s.convertToEmail(msg, folder, section))
+		}
+
+		if err := <-done; err != nil {
+			return nil, err
+		}
+
+		reportProgress(ctx, 0, fmt.Sprintf("%s: 已获取 %d/%d 封邮件", folder, len(emails), len(uids)))
+	}
+
+	return emails, nil
+}
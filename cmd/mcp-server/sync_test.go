@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+)
+
+// newTestIMAP 启动内存IMAP服务器并登录，返回客户端和 INBOX（初始只有一封 UID 6 的邮件）
+func newTestIMAP(t *testing.T) (*client.Client, *memory.Mailbox) {
+	t.Helper()
+	be := memory.New()
+	user, err := be.Login(nil, "username", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mbox, err := user.GetMailbox("INBOX")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := server.New(be)
+	srv.AllowInsecureAuth = true
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	c, err := client.Dial(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Logout() })
+	if err := c.Login("username", "password"); err != nil {
+		t.Fatal(err)
+	}
+	return c, mbox.(*memory.Mailbox)
+}
+
+func TestSyncFolderEmptyResync(t *testing.T) {
+	c, mbox := newTestIMAP(t)
+	s := &MCPServer{}
+	ctx := context.Background()
+	since := time.Now().AddDate(0, 0, 1) // 旧邮件都早于 start_date
+
+	first, err := s.syncFolder(ctx, c, "INBOX", folderCheckpoint{}, since, 100)
+	if err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if !first.resynced || len(first.emails) != 0 {
+		t.Fatalf("first sync = resynced %v, %d emails; want resync with no emails", first.resynced, len(first.emails))
+	}
+	if want := (folderCheckpoint{UIDValidity: 1, LastUID: 6}); first.checkpoint != want {
+		t.Fatalf("checkpoint after empty resync = %+v, want %+v", first.checkpoint, want)
+	}
+
+	// 没有新邮件时不能退化为搜索 1:* 拉取旧邮件
+	second, err := s.syncFolder(ctx, c, "INBOX", first.checkpoint, since, 100)
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if second.resynced || len(second.emails) != 0 || second.checkpoint != first.checkpoint {
+		t.Fatalf("second sync = resynced %v, %d emails, checkpoint %+v; want nothing new",
+			second.resynced, len(second.emails), second.checkpoint)
+	}
+
+	body := "From: hr@acme.com\r\nSubject: Interview invitation\r\nMessage-ID: <new@acme.com>\r\n" +
+		"Content-Type: text/plain\r\n\r\nPlease pick a time."
+	if err := mbox.CreateMessage(nil, time.Now(), strings.NewReader(body)); err != nil {
+		t.Fatal(err)
+	}
+
+	third, err := s.syncFolder(ctx, c, "INBOX", second.checkpoint, since, 100)
+	if err != nil {
+		t.Fatalf("third sync: %v", err)
+	}
+	if len(third.emails) != 1 || third.emails[0].Subject != "Interview invitation" {
+		t.Fatalf("third sync emails = %+v, want only the new message", third.emails)
+	}
+	if third.checkpoint.LastUID != 7 {
+		t.Errorf("LastUID = %d, want 7", third.checkpoint.LastUID)
+	}
+}
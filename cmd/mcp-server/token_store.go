@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenStore 按账号保存OAuth令牌，文件内容使用AES-GCM加密。
+//
+// 未设置 JOBTRACKER_TOKEN_KEY 时密钥保存在同一目录的 token.key 中，能读取 tokens.enc
+// 的人通常也能读取密钥，此时加密只能防止单独泄露令牌文件（如只备份或误传了这一个文件），
+// 不能防止本机其他用户或进程读取令牌。需要真正的保护时请设置 JOBTRACKER_TOKEN_KEY。
+type TokenStore struct {
+	path    string
+	keyPath string
+	mu      sync.Mutex
+}
+
+// 加密令牌文件的内容结构（解密后）
+type tokenFile struct {
+	Accounts map[string]*oauth2.Token `json:"accounts"`
+}
+
+// 创建令牌存储，dir 下会生成 tokens.enc 和 token.key
+func NewTokenStore(dir string) *TokenStore {
+	return &TokenStore{
+		path:    filepath.Join(dir, "tokens.enc"),
+		keyPath: filepath.Join(dir, "token.key"),
+	}
+}
+
+// Load 读取指定账号的令牌，不存在时返回 nil
+func (ts *TokenStore) Load(account string) (*oauth2.Token, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	data, err := ts.read()
+	if err != nil {
+		return nil, err
+	}
+	return data.Accounts[normalizeAccount(account)], nil
+}
+
+// Save 写入指定账号的令牌。刷新响应里可能没有refresh_token，此时沿用旧值
+func (ts *TokenStore) Save(account string, token *oauth2.Token) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	data, err := ts.read()
+	if err != nil {
+		return err
+	}
+
+	key := normalizeAccount(account)
+	if old := data.Accounts[key]; old != nil && token.RefreshToken == "" {
+		copied := *token
+		copied.RefreshToken = old.RefreshToken
+		token = &copied
+	}
+	data.Accounts[key] = token
+
+	return ts.write(data)
+}
+
+// Delete 删除指定账号的令牌
+func (ts *TokenStore) Delete(account string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	data, err := ts.read()
+	if err != nil {
+		return err
+	}
+	delete(data.Accounts, normalizeAccount(account))
+	return ts.write(data)
+}
+
+func (ts *TokenStore) read() (*tokenFile, error) {
+	data := &tokenFile{Accounts: make(map[string]*oauth2.Token)}
+
+	ciphertext, err := os.ReadFile(ts.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read token store: %w", err)
+	}
+
+	plaintext, err := ts.decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(plaintext, data); err != nil {
+		return nil, fmt.Errorf("parse token store: %w", err)
+	}
+	if data.Accounts == nil {
+		data.Accounts = make(map[string]*oauth2.Token)
+	}
+	return data, nil
+}
+
+func (ts *TokenStore) write(data *tokenFile) error {
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal token store: %w", err)
+	}
+
+	ciphertext, err := ts.encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ts.path), 0o700); err != nil {
+		return fmt.Errorf("create token dir: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免写到一半时损坏已有令牌
+	tmp := ts.path + ".tmp"
+	if err := os.WriteFile(tmp, ciphertext, 0o600); err != nil {
+		return fmt.Errorf("write token store: %w", err)
+	}
+	return os.Rename(tmp, ts.path)
+}
+
+func (ts *TokenStore) encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := ts.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (ts *TokenStore) decrypt(ciphertext []byte) ([]byte, error) {
+	gcm, err := ts.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("token store is corrupted")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt token store (密钥不匹配?): %w", err)
+	}
+	return plaintext, nil
+}
+
+func (ts *TokenStore) cipher() (cipher.AEAD, error) {
+	key, err := ts.key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// key 返回加密密钥：优先使用环境变量 JOBTRACKER_TOKEN_KEY（任意口令），
+// 否则读取或生成与令牌文件放在一起的本地密钥文件
+func (ts *TokenStore) key() ([]byte, error) {
+	if passphrase := os.Getenv("JOBTRACKER_TOKEN_KEY"); passphrase != "" {
+		sum := sha256.Sum256([]byte(passphrase))
+		return sum[:], nil
+	}
+
+	if content, err := os.ReadFile(ts.keyPath); err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid token key file %s", ts.keyPath)
+		}
+		return key, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read token key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("generate token key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(ts.keyPath), 0o700); err != nil {
+		return nil, fmt.Errorf("create token dir: %w", err)
+	}
+	if err := os.WriteFile(ts.keyPath, []byte(hex.EncodeToString(key)), 0o600); err != nil {
+		return nil, fmt.Errorf("write token key: %w", err)
+	}
+	log.Printf("警告: 未设置 JOBTRACKER_TOKEN_KEY，令牌加密密钥保存在 %s，与令牌文件在同一目录", ts.keyPath)
+	return key, nil
+}
+
+func normalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestTokenStoreRoundTrip(t *testing.T) {
+	t.Setenv("JOBTRACKER_TOKEN_KEY", "")
+	dir := t.TempDir()
+	store := NewTokenStore(dir)
+
+	missing, err := store.Load(testAccount)
+	if err != nil || missing != nil {
+		t.Fatalf("Load on empty store = %v, %v", missing, err)
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  "secret-access",
+		RefreshToken: "secret-refresh",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour).Round(time.Second),
+	}
+	if err := store.Save(testAccount, token); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// 账号不区分大小写，新建的存储实例使用同一个密钥文件
+	loaded, err := NewTokenStore(dir).Load("  user@example.COM ")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded == nil || loaded.AccessToken != token.AccessToken || loaded.RefreshToken != token.RefreshToken ||
+		!loaded.Expiry.Equal(token.Expiry) {
+		t.Fatalf("loaded token = %+v, want %+v", loaded, token)
+	}
+
+	// 磁盘上的内容是密文
+	data, err := os.ReadFile(filepath.Join(dir, "tokens.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret-access")) || bytes.Contains(data, []byte("secret-refresh")) {
+		t.Error("token store contains plaintext tokens")
+	}
+	for _, name := range []string{"tokens.enc", "token.key"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("%s permissions = %o, want 600", name, perm)
+		}
+	}
+
+	if err := store.Delete(testAccount); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if deleted, _ := store.Load(testAccount); deleted != nil {
+		t.Error("token still present after Delete")
+	}
+}
+
+func TestTokenStoreKeepsRefreshToken(t *testing.T) {
+	t.Setenv("JOBTRACKER_TOKEN_KEY", "")
+	store := NewTokenStore(t.TempDir())
+
+	if err := store.Save(testAccount, &oauth2.Token{AccessToken: "a1", RefreshToken: "r1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(testAccount, &oauth2.Token{AccessToken: "a2"}); err != nil {
+		t.Fatal(err)
+	}
+	token, err := store.Load(testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "a2" || token.RefreshToken != "r1" {
+		t.Errorf("token = %+v, want access a2 with refresh r1", token)
+	}
+}
+
+func TestTokenStorePassphrase(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("JOBTRACKER_TOKEN_KEY", "correct horse")
+	if err := NewTokenStore(dir).Save(testAccount, &oauth2.Token{AccessToken: "a1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "token.key")); !os.IsNotExist(err) {
+		t.Error("key file created although JOBTRACKER_TOKEN_KEY is set")
+	}
+
+	// 口令不同则无法解密
+	t.Setenv("JOBTRACKER_TOKEN_KEY", "battery staple")
+	if _, err := NewTokenStore(dir).Load(testAccount); err == nil {
+		t.Error("Load succeeded with the wrong passphrase")
+	}
+
+	t.Setenv("JOBTRACKER_TOKEN_KEY", "correct horse")
+	token, err := NewTokenStore(dir).Load(testAccount)
+	if err != nil || token == nil || token.AccessToken != "a1" {
+		t.Errorf("Load with the right passphrase = %+v, %v", token, err)
+	}
+}
+
+func TestTokenStoreCorrupted(t *testing.T) {
+	t.Setenv("JOBTRACKER_TOKEN_KEY", "")
+	dir := t.TempDir()
+	store := NewTokenStore(dir)
+	if err := store.Save(testAccount, &oauth2.Token{AccessToken: "a1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "tokens.enc")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff // 篡改密文，GCM认证失败
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(testAccount); err == nil {
+		t.Error("Load succeeded on tampered ciphertext")
+	}
+
+	if err := os.WriteFile(path, []byte("short"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(testAccount); err == nil {
+		t.Error("Load succeeded on truncated ciphertext")
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tool MCP工具定义
+type Tool struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+type ListToolsResult struct {
+	Tools []Tool `json:"tools"`
+}
+
+type CallToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// ContentBlock 工具返回的内容块
+type ContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+type CallToolResult struct {
+	Content           []ContentBlock `json:"content"`
+	StructuredContent interface{}    `json:"structuredContent,omitempty"`
+	IsError           bool           `json:"isError,omitempty"`
+}
+
+// 工具参数（日期使用字符串，支持 YYYY-MM-DD 或 RFC3339）
+type fetchToolArgs struct {
+	Email     string   `json:"email"`
+	Provider  string   `json:"provider"`
+	StartDate string   `json:"start_date"`
+	EndDate   string   `json:"end_date"`
+	MaxEmails int      `json:"max_emails"`
+	Folders   []string `json:"folders"`
+	Keywords  []string `json:"keywords"`
+	Query     string   `json:"query"`
+	Cursor    string   `json:"cursor"`
+	PageToken string   `json:"page_token"`
+
+	// 发件人域名和主题片段，与关键词任一匹配即可
+	SenderDomains   []string `json:"sender_domains"`
+	SubjectPatterns []string `json:"subject_patterns"`
+}
+
+var providerEnum = []string{"gmail", "outlook", "yahoo", "chinese", "custom"}
+
+// tools 返回服务器提供的工具列表
+func (s *MCPServer) tools() []Tool {
+	fetchProperties := func() map[string]interface{} {
+		return map[string]interface{}{
+			"email": map[string]interface{}{
+				"type":        "string",
+				"description": "邮箱地址",
+			},
+			"provider": map[string]interface{}{
+				"type":        "string",
+				"enum":        providerEnum,
+				"description": "邮箱提供商，留空时根据邮箱域名推断",
+			},
+			"start_date": map[string]interface{}{
+				"type":        "string",
+				"description": "开始日期（YYYY-MM-DD 或 RFC3339），默认7天前",
+			},
+			"end_date": map[string]interface{}{
+				"type":        "string",
+				"description": "结束日期（YYYY-MM-DD 或 RFC3339，包含当天），默认今天",
+			},
+			"max_emails": map[string]interface{}{
+				"type":        "integer",
+				"minimum":     1,
+				"maximum":     maxPageSize,
+				"description": "本页最多返回的邮件数量（从新到旧），默认50，单页上限200",
+			},
+			"folders": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "要抓取的文件夹，默认 INBOX",
+			},
+		}
+	}
+
+	pageTokenProperty := map[string]interface{}{
+		"type":        "string",
+		"description": "上一页返回的 next_page_token，其余参数需与上一页一致",
+	}
+
+	listProperties := fetchProperties()
+	listProperties["page_token"] = pageTokenProperty
+
+	searchProperties := fetchProperties()
+	searchProperties["page_token"] = pageTokenProperty
+	searchProperties["query"] = map[string]interface{}{
+		"type":        "string",
+		"description": "搜索关键词，多个关键词用空格分隔，任一匹配即可",
+	}
+	searchProperties["keywords"] = map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string"},
+		"description": "搜索关键词列表，任一匹配即可（与 query 合并）",
+	}
+	searchProperties["sender_domains"] = map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string"},
+		"description": "发件人域名，如 greenhouse.io",
+	}
+	searchProperties["subject_patterns"] = map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string"},
+		"description": "主题中包含的文字，如 \"面试邀请\"",
+	}
+
+	syncProperties := fetchProperties()
+	delete(syncProperties, "end_date")
+	syncProperties["start_date"] = map[string]interface{}{
+		"type":        "string",
+		"description": "首次同步（或文件夹UIDVALIDITY变化）时的开始日期，默认7天前",
+	}
+	syncProperties["max_emails"] = map[string]interface{}{
+		"type":        "integer",
+		"minimum":     1,
+		"maximum":     1000,
+		"description": "最多返回的新邮件数量，默认100",
+	}
+	syncProperties["cursor"] = map[string]interface{}{
+		"type":        "string",
+		"description": "上次 email_sync 返回的游标；留空时使用服务器保存的检查点",
+	}
+
+	return []Tool{
+		{
+			Name:        "email_login",
+			Title:       "邮箱登录",
+			Description: "为邮箱账号启动登录流程。Gmail/Outlook 返回需要在浏览器中打开的OAuth授权地址；其他邮箱使用服务器配置的应用密码。",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"email": map[string]interface{}{
+						"type":        "string",
+						"description": "邮箱地址",
+					},
+					"provider": map[string]interface{}{
+						"type":        "string",
+						"enum":        providerEnum,
+						"description": "邮箱提供商，留空时根据邮箱域名推断",
+					},
+				},
+				"required": []string{"email"},
+			},
+		},
+		{
+			Name:        "email_fetch",
+			Title:       "抓取邮件",
+			Description: "按时间范围抓取邮件（从新到旧），返回发件人、主题、日期和解码后的正文。结果有 next_page_token 时可用它获取下一页。",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": listProperties,
+				"required":   []string{"email"},
+			},
+		},
+		{
+			Name:        "email_search",
+			Title:       "搜索邮件",
+			Description: "在时间范围内按关键词（匹配主题、发件人和正文）、发件人域名或主题搜索邮件，任一条件匹配即可。搜索在邮件服务器上执行，服务器不支持时在本地过滤。",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": searchProperties,
+				"required":   []string{"email"},
+			},
+		},
+		{
+			Name:        "email_sync",
+			Title:       "增量同步邮件",
+			Description: "只返回上次同步之后收到的新邮件，以及用于下次同步的不透明游标。has_more 为 true 时可用新游标继续同步。",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": syncProperties,
+				"required":   []string{"email"},
+			},
+		},
+	}
+}
+
+// handleToolsCall 执行工具。工具本身的失败通过 isError 返回，而不是JSON-RPC错误
+func (s *MCPServer) handleToolsCall(ctx context.Context, params json.RawMessage) (*CallToolResult, error) {
+	var call CallToolParams
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, invalidParams("invalid tools/call parameters")
+	}
+
+	switch call.Name {
+	case "email_login", "email_fetch", "email_search", "email_sync":
+	default:
+		return nil, invalidParams(fmt.Sprintf("unknown tool: %s", call.Name))
+	}
+
+	var args fetchToolArgs
+	if len(call.Arguments) > 0 {
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return nil, invalidParams(fmt.Sprintf("invalid arguments for tool %s", call.Name))
+		}
+	}
+	if args.Email == "" {
+		return nil, invalidParams("email is required")
+	}
+	if args.Provider == "" {
+		args.Provider = inferProvider(args.Email)
+	}
+
+	switch call.Name {
+	case "email_login":
+		session, err := s.handleLogin(mustMarshal(LoginParams{Provider: args.Provider, Email: args.Email}))
+		if err != nil {
+			return toolError(err), nil
+		}
+		text := session.Message
+		if session.LoginURL != "" {
+			text += "\n" + session.LoginURL
+		}
+		return &CallToolResult{
+			Content:           []ContentBlock{{Type: "text", Text: text}},
+			StructuredContent: session,
+		}, nil
+
+	case "email_fetch", "email_search":
+		params, err := args.fetchParams()
+		if err != nil {
+			return nil, invalidParams(err.Error())
+		}
+		if call.Name == "email_search" && newSearchFilter(params).empty() {
+			return nil, invalidParams("query, keywords, sender_domains or subject_patterns is required")
+		}
+
+		result, err := s.fetchEmails(ctx, params)
+		if err != nil {
+			var rpcErr *rpcError
+			if errors.As(err, &rpcErr) {
+				return nil, err
+			}
+			return toolError(err), nil
+		}
+		return emailsResult(result), nil
+
+	case "email_sync":
+		params, err := args.fetchParams()
+		if err != nil {
+			return nil, invalidParams(err.Error())
+		}
+
+		result, err := s.syncEmails(ctx, SyncParams{
+			Provider:  params.Provider,
+			Email:     params.Email,
+			Folders:   params.Folders,
+			StartDate: params.StartDate,
+			MaxEmails: params.MaxEmails,
+			Cursor:    args.Cursor,
+		})
+		if err != nil {
+			return toolError(err), nil
+		}
+		return &CallToolResult{
+			Content:           []ContentBlock{{Type: "text", Text: string(mustMarshal(result))}},
+			StructuredContent: result,
+		}, nil
+
+	default:
+		return nil, invalidParams(fmt.Sprintf("unknown tool: %s", call.Name))
+	}
+}
+
+// fetchParams 把工具参数转换为抓取参数并填充默认值
+func (a fetchToolArgs) fetchParams() (FetchParams, error) {
+	params := FetchParams{
+		Provider:  a.Provider,
+		Email:     a.Email,
+		MaxEmails: a.MaxEmails,
+		Folders:   a.Folders,
+		Keywords:  append(a.Keywords, strings.Fields(a.Query)...),
+		PageToken: a.PageToken,
+
+		SenderDomains:   a.SenderDomains,
+		SubjectPatterns: a.SubjectPatterns,
+	}
+
+	// 默认范围取整到天（IMAP 搜索本身只按日期），同一天内翻页时查询指纹不变，page_token 仍然有效
+	today := startOfDay(time.Now())
+	var err error
+	if params.StartDate, err = parseToolDate(a.StartDate, today.AddDate(0, 0, -7)); err != nil {
+		return params, fmt.Errorf("invalid start_date: %v", err)
+	}
+	if params.EndDate, err = parseToolDate(a.EndDate, today); err != nil {
+		return params, fmt.Errorf("invalid end_date: %v", err)
+	}
+	if params.MaxEmails <= 0 {
+		params.MaxEmails = 50
+	}
+	if len(params.Folders) == 0 {
+		params.Folders = []string{"INBOX"}
+	}
+	return params, nil
+}
+
+// startOfDay 本地日期当天零点，与 YYYY-MM-DD 格式的参数一样用UTC表示
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func parseToolDate(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// containsKeyword 判断主题、发件人或正文中是否包含任一关键词（不区分大小写）
+func containsKeyword(email Email, keywords []string) bool {
+	haystack := strings.ToLower(strings.Join(
+		[]string{email.Subject, email.From, email.BodyText, email.BodyHTML}, "\n"))
+	for _, keyword := range keywords {
+		if keyword != "" && strings.Contains(haystack, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// emailsResult 把一页邮件包装为工具结果：结构化内容 + 等价的JSON文本块
+func emailsResult(result *FetchResult) *CallToolResult {
+	emails := result.Emails
+	if emails == nil {
+		emails = []Email{}
+	}
+	structured := map[string]interface{}{
+		"count":  len(emails),
+		"emails": emails,
+	}
+	if result.NextPageToken != "" {
+		structured["next_page_token"] = result.NextPageToken
+	}
+	return &CallToolResult{
+		Content:           []ContentBlock{{Type: "text", Text: string(mustMarshal(structured))}},
+		StructuredContent: structured,
+	}
+}
+
+func toolError(err error) *CallToolResult {
+	return &CallToolResult{
+		Content: []ContentBlock{{Type: "text", Text: err.Error()}},
+		IsError: true,
+	}
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("marshal %T: %v", v, err))
+	}
+	return data
+}
+
+// inferProvider 根据邮箱域名推断提供商
+func inferProvider(email string) string {
+	email = strings.ToLower(email)
+
+	switch {
+	case strings.HasSuffix(email, "@gmail.com"), strings.HasSuffix(email, "@googlemail.com"):
+		return "gmail"
+	case strings.HasSuffix(email, "@outlook.com"), strings.HasSuffix(email, "@hotmail.com"), strings.HasSuffix(email, "@live.com"):
+		return "outlook"
+	case strings.Contains(email, "@yahoo."):
+		return "yahoo"
+	default:
+		return "custom"
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSE流的心跳间隔，避免长时间抓取时被代理断开
+const sseHeartbeatInterval = 15 * time.Second
+
+// handleMCP 实现MCP的 Streamable HTTP 传输：
+// POST 发送JSON-RPC消息，客户端在 Accept 中声明 text/event-stream 时，
+// 响应以SSE流返回，期间推送进度通知；否则直接返回JSON。
+func (s *MCPServer) handleMCP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept, Mcp-Session-Id, Mcp-Protocol-Version")
+	w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id")
+
+	switch r.Method {
+	case http.MethodOptions:
+		return
+	case http.MethodPost:
+	case http.MethodDelete:
+		s.endHTTPSession(r.Header.Get("Mcp-Session-Id"))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		// 不提供服务器主动发起的GET流
+		w.Header().Set("Allow", "POST, DELETE, OPTIONS")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 旧版客户端不带会话头，仍然允许（共用一个空会话）；带了未知会话ID则要求重新初始化
+	sessionID := r.Header.Get("Mcp-Session-Id")
+	if sessionID != "" && !s.hasHTTPSession(sessionID) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	ctx := withSession(r.Context(), sessionID)
+
+	var req MCPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		s.sendError(w, nil, codeParseError, "Parse error")
+		return
+	}
+
+	if len(req.ID) == 0 {
+		// 通知消息不需要响应
+		s.dispatch(ctx, &req)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if req.Method == "initialize" {
+		// 先处理再决定是否分配会话ID，初始化失败时不返回会话头。
+		// initialize 没有进度通知，直接返回JSON
+		resp := s.dispatch(ctx, &req)
+		if resp.Error == nil {
+			w.Header().Set("Mcp-Session-Id", s.newHTTPSession())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	flusher, canFlush := w.(http.Flusher)
+	if !canFlush || !acceptsEventStream(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.dispatch(ctx, &req))
+		return
+	}
+
+	s.serveEventStream(ctx, w, flusher, &req)
+}
+
+// serveEventStream 以SSE返回单个请求的进度通知和最终响应
+func (s *MCPServer) serveEventStream(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, req *MCPRequest) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var mu sync.Mutex
+	writeEvent := func(v interface{}) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(sseHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mu.Lock()
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+				mu.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// 客户端断开连接时请求上下文会被取消，进行中的抓取随之停止
+	ctx = withNotifier(ctx, func(notification *MCPNotification) {
+		writeEvent(notification)
+	})
+
+	if resp := s.dispatch(ctx, req); resp != nil {
+		writeEvent(resp)
+	}
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		if strings.Contains(accept, "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MCPServer) newHTTPSession() string {
+	id := randomToken()
+
+	s.mcp.mu.Lock()
+	defer s.mcp.mu.Unlock()
+	if s.mcp.httpSessions == nil {
+		s.mcp.httpSessions = make(map[string]bool)
+	}
+	s.mcp.httpSessions[id] = true
+	return id
+}
+
+func (s *MCPServer) hasHTTPSession(id string) bool {
+	s.mcp.mu.Lock()
+	defer s.mcp.mu.Unlock()
+	return s.mcp.httpSessions[id]
+}
+
+func (s *MCPServer) endHTTPSession(id string) {
+	s.mcp.mu.Lock()
+	defer s.mcp.mu.Unlock()
+	delete(s.mcp.httpSessions, id)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"sync"
+)
+
+// serveStdio 以换行分隔的JSON-RPC消息在标准输入/输出上提供MCP服务。
+// 标准输出只能写协议消息，日志必须写到标准错误。
+func (s *MCPServer) serveStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	encoder := json.NewEncoder(out)
+
+	var writeMu sync.Mutex
+	write := func(resp *MCPResponse) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		// Encode 会在每条消息末尾追加换行
+		if err := encoder.Encode(resp); err != nil {
+			log.Printf("写入stdio响应失败: %v", err)
+		}
+	}
+
+	// 进度等通知与响应写入同一输出流
+	ctx = withNotifier(ctx, func(notification *MCPNotification) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := encoder.Encode(notification); err != nil {
+			log.Printf("写入stdio通知失败: %v", err)
+		}
+	})
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var req MCPRequest
+			if jsonErr := json.Unmarshal(line, &req); jsonErr != nil {
+				write(errorResponse(nil, codeParseError, "Parse error"))
+			} else {
+				// 每个请求单独处理，长时间的抓取不会阻塞 ping 等其他请求
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if resp := s.dispatch(ctx, &req); resp != nil {
+						write(resp)
+					}
+				}()
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
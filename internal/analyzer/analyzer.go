@@ -1,14 +1,14 @@
 package analyzer
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/YKarmar/JobTracker/internal/types"
@@ -16,90 +16,136 @@ import (
 
 // LLM客户端配置
 type LLMConfig struct {
+	// 后端：openai（默认）、anthropic 或 ollama
+	Provider    string  `json:"provider"`
 	APIBase     string  `json:"api_base"`
 	APIKey      string  `json:"api_key"`
 	Model       string  `json:"model"`
 	Temperature float64 `json:"temperature"`
 	MaxTokens   int     `json:"max_tokens"`
+	// 提示词中邮件正文的token预算（估算），默认等于 MaxTokens。清理后仍超出时分段摘录
+	BodyTokens int `json:"body_tokens"`
+
+	// 并发分析的worker数量，默认4
+	Concurrency int `json:"concurrency"`
+	// 每分钟请求数和token数上限，0 表示不限制
+	RequestsPerMinute int `json:"requests_per_minute"`
+	TokensPerMinute   int `json:"tokens_per_minute"`
+
+	// 结构化输出模式：json_schema（默认）、json_object 或 text，不支持时自动降级
+	ResponseFormat string `json:"response_format"`
+	// is_job_related 为 true 且置信度不低于该值才视为求职邮件，默认0.5
+	MinConfidence float64 `json:"min_confidence"`
 }
 
-// LLM请求和响应结构
-type LLMRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature"`
-	MaxTokens   int       `json:"max_tokens"`
-}
+// 默认并发数、置信度阈值和正文token预算
+const (
+	defaultConcurrency   = 4
+	defaultMinConfidence = 0.5
+	defaultBodyTokens    = 2000
+)
 
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
 }
 
-type LLMResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-}
-
 // 求职邮件分析器
 type JobAnalyzer struct {
-	llmConfig  LLMConfig
-	httpClient *http.Client
+	llmConfig LLMConfig
+	provider  Provider
+	limiter   *rateLimiter
+
+	// 当前使用的结构化输出模式，遇到不支持时降级
+	formatMu sync.Mutex
+	format   string
+
+	// OnResult 在每封邮件分析完成后调用（可选），done 为已完成数量。调用是串行的
+	OnResult func(done, total int, result EmailResult)
+
+	// Cache 回复缓存（可选），为 nil 时每封邮件都调用LLM
+	Cache *ResponseCache
+
+	statsMu sync.Mutex
+	stats   UsageStats
 }
 
-// 创建求职分析器
-func NewJobAnalyzer(config LLMConfig) *JobAnalyzer {
-	return &JobAnalyzer{
-		llmConfig: config,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-	}
+// UsageStats 累计的LLM调用和token用量
+type UsageStats struct {
+	Requests  int   // 实际发出的请求数（含修复请求）
+	Usage     Usage // 实际消耗的token
+	CacheHits int   // 命中缓存的邮件数
+	Saved     Usage // 命中缓存节省的token（按缓存记录的原始用量）
 }
 
-// 分析邮件是否与求职相关
-func (ja *JobAnalyzer) IsJobRelated(ctx context.Context, email types.Email) (bool, error) {
-	prompt := fmt.Sprintf(`
-请判断以下邮件是否与求职、招聘、面试相关。
+// Stats 目前为止的用量统计
+func (ja *JobAnalyzer) Stats() UsageStats {
+	ja.statsMu.Lock()
+	defer ja.statsMu.Unlock()
+	return ja.stats
+}
 
-邮件信息：
-发件人: %s
-主题: %s
-正文: %s
+// 创建求职分析器，llm.provider 不受支持时返回错误
+func NewJobAnalyzer(config LLMConfig) (*JobAnalyzer, error) {
+	provider, err := NewProvider(config, &http.Client{Timeout: 60 * time.Second})
+	if err != nil {
+		return nil, err
+	}
 
-请仅回答 "是" 或 "否"，不要包含其他内容。
-`, email.From, email.Subject, truncateText(email.BodyText, 1000))
+	return NewJobAnalyzerWithProvider(config, provider), nil
+}
 
-	response, err := ja.callLLM(ctx, prompt)
-	if err != nil {
-		return false, fmt.Errorf("LLM call failed: %w", err)
+// 使用指定后端创建求职分析器（如测试中的替身）
+func NewJobAnalyzerWithProvider(config LLMConfig, provider Provider) *JobAnalyzer {
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency
+	}
+	if config.MinConfidence <= 0 {
+		config.MinConfidence = defaultMinConfidence
+	}
+	if config.ResponseFormat == "" {
+		config.ResponseFormat = FormatJSONSchema
+	}
+	if config.BodyTokens <= 0 {
+		config.BodyTokens = config.MaxTokens
+	}
+	if config.BodyTokens <= 0 {
+		config.BodyTokens = defaultBodyTokens
 	}
 
-	response = strings.TrimSpace(strings.ToLower(response))
-	return response == "是" || response == "yes" || strings.Contains(response, "是"), nil
+	return &JobAnalyzer{
+		llmConfig: config,
+		provider:  provider,
+		limiter:   newRateLimiter(config.RequestsPerMinute, config.TokensPerMinute),
+		format:    config.ResponseFormat,
+	}
 }
 
-// 解析求职相关邮件的详细信息
-func (ja *JobAnalyzer) AnalyzeJobEmail(ctx context.Context, email types.Email) (*types.JobApplication, error) {
-	prompt := fmt.Sprintf(`
-请分析以下求职相关的邮件，提取出公司名称、职位、当前状态等信息。
+// 分类与信息提取的提示词，一次调用同时判断是否求职相关并提取详情
+const analysisPrompt = `
+请分析以下邮件：先判断它是否与求职、招聘、面试相关，如果相关再提取公司名称、职位、当前状态等信息。
 
 邮件信息：
 发件人: %s
 主题: %s
 日期: %s
 正文: %s
-
-请按以下JSON格式返回分析结果：
+%s
+请只返回一个JSON对象，不要包含其他内容，格式如下：
 {
-  "company": "公司名称",
-  "position": "职位名称", 
-  "status": "状态(APPLIED/OA/INTERVIEW/OFFER/REJECTED/WITHDRAWN/OTHER)",
-  "location": "工作地点(可选)",
-  "description": "简短描述当前状态"
+  "is_job_related": true 或 false,
+  "confidence": 0到1之间的数字，表示判断的把握,
+  "company": "公司名称（不相关时为空字符串）",
+  "position": "职位名称（不相关时为空字符串）",
+  "requisition_id": "职位编号，如 R12345 或 JR-2024-001（邮件中没有时为空字符串）",
+  "status": "APPLIED/OA/INTERVIEW/OFFER/REJECTED/WITHDRAWN/OTHER 之一（不相关时为 OTHER）",
+  "location": "工作地点（未知时为空字符串）",
+  "description": "简短描述当前状态",
+  "event_start": "笔试/面试开始时间，RFC3339格式如 2024-03-15T14:00:00+08:00（没有时为空字符串）",
+  "event_end": "笔试/面试结束时间，格式同上（没有时为空字符串）",
+  "deadline": "需要完成的截止时间，如笔试截止、offer答复截止，格式同上（没有时为空字符串）",
+  "timezone": "邮件中时间对应的IANA时区，如 Asia/Shanghai（未知时为空字符串）",
+  "meeting_url": "视频会议链接，如 Zoom/Teams/腾讯会议（没有时为空字符串）"
 }
 
 状态说明：
@@ -111,144 +157,444 @@ func (ja *JobAnalyzer) AnalyzeJobEmail(ctx context.Context, email types.Email) (
 - WITHDRAWN: 撤回申请
 - OTHER: 其他状态
 
-请确保返回有效的JSON格式，不要包含其他内容。
-`, email.From, email.Subject, email.Date.Format("2006-01-02"), truncateText(email.BodyText, 2000))
+招聘网站的职位推荐、营销邮件和新闻简报不算求职相关。
+时间没有写明时区时按邮件日期的时区理解。
+`
 
-	response, err := ja.callLLM(ctx, prompt)
-	if err != nil {
-		return nil, fmt.Errorf("LLM analysis failed: %w", err)
-	}
+// 长邮件分段摘录的提示词（map），各段的摘录合并后代替正文进行分析（reduce）
+const chunkPrompt = `
+下面是一封较长邮件的第 %d/%d 部分。请摘录其中与求职相关的信息：公司名称、职位、职位编号、申请状态、
+笔试/面试时间、截止时间、时区、会议链接和工作地点，总长度不超过 %d 字。时间和链接保持原文，不要推测。
+这部分没有相关信息时只回复"无"。
 
-	// 解析JSON响应
-	var result struct {
-		Company     string `json:"company"`
-		Position    string `json:"position"`
-		Status      string `json:"status"`
-		Location    string `json:"location"`
-		Description string `json:"description"`
+发件人: %s
+主题: %s
+内容:
+%s
+`
+
+// 回复无法通过校验时，最多追加几轮修复请求
+const maxRepairAttempts = 2
+
+// 长邮件最多分析的段数，超出部分忽略（招聘信息通常在邮件开头）
+const maxChunks = 8
+
+// 单封邮件的分类结果
+type Classification struct {
+	IsJobRelated bool
+	Confidence   float64
+	// 求职相关时非空
+	Application *types.JobApplication
+	// 结果是否来自缓存，以及得到它消耗的token（命中缓存时为缓存记录的原始用量）
+	Cached bool
+	Usage  Usage
+}
+
+// 一次LLM调用完成分类和信息提取。回复不是合法JSON或不符合schema时，
+// 把错误反馈给模型要求修正，最多重试 maxRepairAttempts 次。
+// 设置了 Cache 时，同一提示词的有效回复直接从缓存读取
+func (ja *JobAnalyzer) ClassifyEmail(ctx context.Context, email types.Email) (*Classification, error) {
+	body := cleanBody(emailText(email))
+
+	// 没有时区信息的时间按邮件日期的时区理解
+	loc := email.Date.Location()
+	// 缓存键按清理后的完整正文计算，长邮件命中缓存时也不需要重新分段摘录
+	key := cacheKey(ja.llmConfig, formatPrompt(email, body))
+
+	analysis, usage, cached := ja.cachedAnalysis(key, loc)
+	var messages []Message
+	if analysis == nil {
+		if chunks := splitChunks(body, ja.llmConfig.BodyTokens); len(chunks) > 1 {
+			summary, used, err := ja.summarizeChunks(ctx, email, chunks)
+			usage.add(used)
+			if err != nil {
+				return nil, fmt.Errorf("LLM analysis failed: %w", err)
+			}
+			body = summary
+		}
+		messages = []Message{{Role: "user", Content: formatPrompt(email, body)}}
 	}
 
-	// 清理响应文本，提取JSON部分
-	jsonStr := extractJSON(response)
-	if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
-		return nil, fmt.Errorf("parse JSON response: %w, response: %s", err, response)
+	for attempt := 0; analysis == nil; attempt++ {
+		response, used, err := ja.chat(ctx, messages, true)
+		usage.add(used)
+		if err != nil {
+			return nil, fmt.Errorf("LLM analysis failed: %w", err)
+		}
+
+		analysis, err = parseAnalysis(response, loc)
+		if err == nil {
+			ja.storeAnalysis(key, response, usage)
+			break
+		}
+		if attempt >= maxRepairAttempts {
+			return nil, fmt.Errorf("invalid analysis after %d attempts: %w, response: %s", attempt+1, err, response)
+		}
+
+		messages = append(messages,
+			Message{Role: "assistant", Content: response},
+			Message{Role: "user", Content: fmt.Sprintf("上面的回复不符合要求：%v。请只返回符合格式要求的JSON对象，不要包含其他内容。", err)},
+		)
 	}
 
-	// 验证和标准化状态
-	status := normalizeJobStatus(result.Status)
+	// 置信度阈值在读取结果后应用，调整 min_confidence 不会使缓存失效
+	classification := &Classification{
+		IsJobRelated: analysis.IsJobRelated && analysis.Confidence >= ja.llmConfig.MinConfidence,
+		Confidence:   analysis.Confidence,
+		Cached:       cached,
+		Usage:        usage,
+	}
+	if !classification.IsJobRelated {
+		return classification, nil
+	}
 
-	return &types.JobApplication{
-		Company:     cleanText(result.Company),
-		Position:    cleanText(result.Position),
-		Status:      status,
-		Location:    cleanText(result.Location),
-		Description: cleanText(result.Description),
-		Email:       email,
-		ExtractedAt: time.Now(),
-	}, nil
+	classification.Application = &types.JobApplication{
+		Company:       cleanText(analysis.Company),
+		Position:      cleanText(analysis.Position),
+		RequisitionID: cleanText(analysis.RequisitionID),
+		Status:        normalizeJobStatus(analysis.Status),
+		Location:      cleanText(analysis.Location),
+		Description:   cleanText(analysis.Description),
+		EventStart:    analysis.start,
+		EventEnd:      analysis.end,
+		Deadline:      analysis.deadline,
+		Timezone:      analysis.Timezone,
+		MeetingURL:    strings.TrimSpace(analysis.MeetingURL),
+		Email:         email,
+		ExtractedAt:   time.Now(),
+	}
+	applyInvite(classification.Application, email.Invites)
+	return classification, nil
 }
 
-// 批量分析邮件
-func (ja *JobAnalyzer) AnalyzeEmails(ctx context.Context, emails []types.Email) ([]types.JobApplication, error) {
-	var jobApplications []types.JobApplication
+// formatPrompt 生成分析提示词
+func formatPrompt(email types.Email, body string) string {
+	return fmt.Sprintf(analysisPrompt,
+		email.From, email.Subject, email.Date.Format("2006-01-02 15:04 -0700"), body,
+		formatInvites(email.Invites))
+}
 
-	for i, email := range emails {
-		select {
-		case <-ctx.Done():
-			return jobApplications, ctx.Err()
-		default:
+// summarizeChunks 分段摘录长邮件中的求职信息（map），按段落顺序合并为不超过正文预算的文本（reduce）
+func (ja *JobAnalyzer) summarizeChunks(ctx context.Context, email types.Email, chunks []string) (string, Usage, error) {
+	if len(chunks) > maxChunks {
+		chunks = chunks[:maxChunks]
+	}
+	// 每段摘录的长度，合并后不超过预算
+	perChunk := ja.llmConfig.BodyTokens / len(chunks)
+
+	var (
+		usage Usage
+		b     strings.Builder
+	)
+	for i, chunk := range chunks {
+		messages := []Message{{
+			Role:    "user",
+			Content: fmt.Sprintf(chunkPrompt, i+1, len(chunks), perChunk, email.From, email.Subject, chunk),
+		}}
+		content, used, err := ja.chat(ctx, messages, false)
+		usage.add(used)
+		if err != nil {
+			return "", usage, fmt.Errorf("summarize part %d/%d: %w", i+1, len(chunks), err)
 		}
 
-		fmt.Printf("分析邮件 %d/%d: %s\n", i+1, len(emails), email.Subject)
-
-		// 先判断是否与求职相关
-		isJobRelated, err := ja.IsJobRelated(ctx, email)
-		if err != nil {
-			fmt.Printf("检查邮件 %s 失败: %v\n", email.Subject, err)
+		content = strings.TrimSpace(content)
+		if content == "" || content == "无" {
 			continue
 		}
+		fmt.Fprintf(&b, "[第%d/%d部分摘录]\n%s\n", i+1, len(chunks), content)
+	}
 
-		if !isJobRelated {
-			continue
+	if b.Len() == 0 {
+		return "（长邮件中没有摘录到求职相关信息）", usage, nil
+	}
+	return truncateTokens(b.String(), ja.llmConfig.BodyTokens), usage, nil
+}
+
+// cachedAnalysis 从缓存读取并解析回复。没有缓存、缓存读取失败或缓存的回复不再能通过校验时返回 nil
+func (ja *JobAnalyzer) cachedAnalysis(key string, loc *time.Location) (*emailAnalysis, Usage, bool) {
+	if ja.Cache == nil {
+		return nil, Usage{}, false
+	}
+
+	cached, err := ja.Cache.get(key)
+	if err != nil {
+		log.Printf("读取LLM缓存失败: %v", err)
+		return nil, Usage{}, false
+	}
+	if cached == nil {
+		return nil, Usage{}, false
+	}
+	analysis, err := parseAnalysis(cached.Response, loc)
+	if err != nil {
+		return nil, Usage{}, false
+	}
+
+	ja.statsMu.Lock()
+	ja.stats.CacheHits++
+	ja.stats.Saved.add(cached.Usage)
+	ja.statsMu.Unlock()
+	return analysis, cached.Usage, true
+}
+
+// storeAnalysis 缓存通过校验的回复，写入失败只记录日志
+func (ja *JobAnalyzer) storeAnalysis(key, response string, usage Usage) {
+	if ja.Cache == nil {
+		return
+	}
+	err := ja.Cache.put(key, cachedResponse{
+		Response:  response,
+		Model:     ja.llmConfig.Model,
+		Usage:     usage,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("写入LLM缓存失败: %v", err)
+	}
+}
+
+// formatInvites 把日历邀请附加到提示词中
+func formatInvites(invites []types.CalendarInvite) string {
+	if len(invites) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("日历邀请:\n")
+	for _, inv := range invites {
+		fmt.Fprintf(&b, "- %s 开始: %s", inv.Summary, inv.Start.Format(time.RFC3339))
+		if !inv.End.IsZero() {
+			fmt.Fprintf(&b, " 结束: %s", inv.End.Format(time.RFC3339))
+		}
+		if inv.MeetingURL != "" {
+			fmt.Fprintf(&b, " 会议链接: %s", inv.MeetingURL)
 		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
 
-		// 分析求职邮件详情
-		jobApp, err := ja.AnalyzeJobEmail(ctx, email)
-		if err != nil {
-			fmt.Printf("分析邮件 %s 失败: %v\n", email.Subject, err)
+// applyInvite 日历邀请中的时间和链接比模型从正文提取的更可靠，有则覆盖。
+// 取消邀请（METHOD:CANCEL）不作为面试时间
+func applyInvite(app *types.JobApplication, invites []types.CalendarInvite) {
+	for _, inv := range invites {
+		if inv.Method == "CANCEL" {
 			continue
 		}
+		app.EventStart = inv.Start
+		app.EventEnd = inv.End
+		if inv.Timezone != "" {
+			app.Timezone = inv.Timezone
+		}
+		if inv.MeetingURL != "" {
+			app.MeetingURL = inv.MeetingURL
+		}
+		return
+	}
+}
 
-		jobApplications = append(jobApplications, *jobApp)
-		fmt.Printf("发现求职邮件: %s - %s (%s)\n", jobApp.Company, jobApp.Position, jobApp.Status)
+// 单封邮件的分析结果
+type EmailResult struct {
+	Email      types.Email
+	JobRelated bool
+	Confidence float64
+	// 求职相关且分析成功时非空
+	Application *types.JobApplication
+	// 结果是否来自缓存，以及对应的token用量
+	Cached bool
+	Usage  Usage
+	// 判断或分析失败的原因
+	Err error
+}
 
-		// 添加延迟避免API限制
-		time.Sleep(500 * time.Millisecond)
+// 批量分析报告，Results 与输入邮件顺序一致
+type AnalysisReport struct {
+	Results []EmailResult
+}
+
+// 所有分析成功的求职记录，保持输入顺序
+func (r *AnalysisReport) Applications() []types.JobApplication {
+	var applications []types.JobApplication
+	for _, result := range r.Results {
+		if result.Application != nil {
+			applications = append(applications, *result.Application)
+		}
 	}
+	return applications
+}
 
-	return jobApplications, nil
+// 分析失败的邮件
+func (r *AnalysisReport) Failed() []EmailResult {
+	var failed []EmailResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
 }
 
-// 调用LLM API
-func (ja *JobAnalyzer) callLLM(ctx context.Context, prompt string) (string, error) {
-	req := LLMRequest{
-		Model:       ja.llmConfig.Model,
-		Temperature: ja.llmConfig.Temperature,
-		MaxTokens:   ja.llmConfig.MaxTokens,
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-	}
-
-	reqBody, err := json.Marshal(req)
-	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
+// 批量分析邮件：固定数量的worker并发处理，受请求数和token数限速。
+// 单封邮件失败不会中断整体，错误记录在报告中；ctx 取消时返回已完成部分的报告和 ctx.Err()
+func (ja *JobAnalyzer) AnalyzeEmails(ctx context.Context, emails []types.Email) (*AnalysisReport, error) {
+	report := &AnalysisReport{Results: make([]EmailResult, len(emails))}
+
+	jobs := make(chan int)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+
+	for w := 0; w < ja.llmConfig.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := ja.analyzeEmail(ctx, emails[i])
+				report.Results[i] = result
+
+				if ja.OnResult != nil {
+					mu.Lock()
+					done++
+					ja.OnResult(done, len(emails), result)
+					mu.Unlock()
+				}
+			}
+		}()
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", ja.llmConfig.APIBase+"/chat/completions", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+	sent := 0
+feed:
+	for ; sent < len(emails); sent++ {
+		select {
+		case jobs <- sent:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// 取消后未处理的邮件
+	for i := sent; i < len(emails); i++ {
+		report.Results[i] = EmailResult{Email: emails[i], Err: ctx.Err()}
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+ja.llmConfig.APIKey)
+	return report, ctx.Err()
+}
+
+// 分析单封邮件
+func (ja *JobAnalyzer) analyzeEmail(ctx context.Context, email types.Email) EmailResult {
+	result := EmailResult{Email: email}
 
-	resp, err := ja.httpClient.Do(httpReq)
+	classification, err := ja.ClassifyEmail(ctx, email)
 	if err != nil {
-		return "", fmt.Errorf("HTTP request: %w", err)
+		result.Err = err
+		return result
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("LLM API error: %s", string(body))
-	}
+	result.JobRelated = classification.IsJobRelated
+	result.Confidence = classification.Confidence
+	result.Application = classification.Application
+	result.Cached = classification.Cached
+	result.Usage = classification.Usage
+	return result
+}
 
-	var llmResp LLMResponse
-	if err := json.NewDecoder(resp.Body).Decode(&llmResp); err != nil {
-		return "", fmt.Errorf("decode response: %w", err)
+// 调用LLM，返回回复和token用量（后端没有返回用量时按估算）。structured 为 true 时按当前的结构化输出模式请求，
+// 后端不支持该模式（返回400并提到 response_format 等）时自动降级并重试；否则请求纯文本回复
+func (ja *JobAnalyzer) chat(ctx context.Context, messages []Message, structured bool) (string, Usage, error) {
+	// 预计消耗 = 提示词 + 最大输出
+	promptTokens := 0
+	for _, m := range messages {
+		promptTokens += estimateTokens(m.Content)
 	}
 
-	if len(llmResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from LLM")
+	for {
+		if err := ja.limiter.wait(ctx, promptTokens+ja.llmConfig.MaxTokens); err != nil {
+			return "", Usage{}, err
+		}
+
+		req := CompletionRequest{
+			Messages:    messages,
+			Temperature: ja.llmConfig.Temperature,
+			MaxTokens:   ja.llmConfig.MaxTokens,
+			Format:      FormatText,
+		}
+		if structured {
+			req.Format = ja.currentFormat()
+			req.SchemaName = "job_email_analysis"
+			req.Schema = analysisSchema
+		}
+		format := req.Format
+		completion, err := ja.provider.Complete(ctx, req)
+		if err == nil {
+			usage := completion.Usage
+			if usage.Total() == 0 {
+				usage = Usage{PromptTokens: promptTokens, CompletionTokens: estimateTokens(completion.Content)}
+			}
+			ja.statsMu.Lock()
+			ja.stats.Requests++
+			ja.stats.Usage.add(usage)
+			ja.statsMu.Unlock()
+			return completion.Content, usage, nil
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+			format != FormatText && mentionsResponseFormat(apiErr.Body) {
+			ja.downgradeFormat(format)
+			continue
+		}
+		return "", Usage{}, err
 	}
+}
+
+// 结构化输出模式
+const (
+	FormatJSONSchema = "json_schema" // 按 analysisSchema 约束输出
+	FormatJSONObject = "json_object" // JSON模式，只保证是合法JSON
+	FormatText       = "text"        // 不约束输出，依赖提示词和本地校验
+)
 
-	return llmResp.Choices[0].Message.Content, nil
+func (ja *JobAnalyzer) currentFormat() string {
+	ja.formatMu.Lock()
+	defer ja.formatMu.Unlock()
+	return ja.format
 }
 
-// 辅助函数
+// downgradeFormat json_schema -> json_object -> text。
+// 并发请求可能同时失败，只有当前模式仍是 from 时才降级，避免连降两级
+func (ja *JobAnalyzer) downgradeFormat(from string) {
+	ja.formatMu.Lock()
+	defer ja.formatMu.Unlock()
 
-// 截断文本到指定长度
-func truncateText(text string, maxLen int) string {
-	if len(text) <= maxLen {
-		return text
+	if ja.format != from {
+		return
 	}
-	return text[:maxLen] + "..."
+	switch from {
+	case FormatJSONSchema:
+		ja.format = FormatJSONObject
+	default:
+		ja.format = FormatText
+	}
+	log.Printf("LLM服务不支持 response_format=%s，改用 %s", from, ja.format)
 }
 
+// mentionsResponseFormat 错误信息是否与结构化输出参数有关。不匹配单独的 "format"，
+// 避免把 "invalid date format" 之类无关的400错误当作不支持而降级
+func mentionsResponseFormat(body string) bool {
+	body = strings.ToLower(body)
+	for _, param := range []string{"response_format", "json_schema", "json_object", "tool_choice"} {
+		if strings.Contains(body, param) {
+			return true
+		}
+	}
+	return false
+}
+
+// 辅助函数
+
 // 提取JSON字符串
 func extractJSON(text string) string {
 	// 尝试找到JSON对象
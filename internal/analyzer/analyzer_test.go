@@ -0,0 +1,23 @@
+package analyzer
+
+import "testing"
+
+func TestMentionsResponseFormat(t *testing.T) {
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{`{"error":{"message":"'response_format.type' must be 'text'"}}`, true},
+		{`Unsupported value: JSON_SCHEMA`, true},
+		{`json_object is not supported for this model`, true},
+		{`{"error":{"message":"tool_choice is not supported"}}`, true},
+		{`{"error":{"message":"invalid date format in messages[0]"}}`, false},
+		{`{"error":"unknown format for image"}`, false},
+		{`maximum context length exceeded`, false},
+	}
+	for _, tt := range tests {
+		if got := mentionsResponseFormat(tt.body); got != tt.want {
+			t.Errorf("mentionsResponseFormat(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// promptVersion 分析逻辑的版本，计入缓存键。提示词文本本身已计入缓存键，
+// 只有修改了解析或校验规则等不体现在提示词中的逻辑时才需要递增
+const promptVersion = 1
+
+var responsesBucket = []byte("responses") // 缓存键 -> cachedResponse JSON
+
+// Usage token用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+func (u *Usage) add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+}
+
+// cachedResponse 一封邮件通过校验的最终回复，Usage 为得到它消耗的token（含修复请求）
+type cachedResponse struct {
+	Response  string    `json:"response"`
+	Model     string    `json:"model"`
+	Usage     Usage     `json:"usage"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ResponseCache 持久化的LLM回复缓存（bbolt），键为后端、模型、promptVersion 和完整提示词的哈希，
+// 因此修改提示词、换模型或邮件内容变化时自动失效。超过 TTL 的条目视为不存在，打开时清理
+type ResponseCache struct {
+	db  *bolt.DB
+	ttl time.Duration // 0 表示不过期
+}
+
+// OpenResponseCache 打开（必要时创建）缓存文件，并删除已过期的条目
+func OpenResponseCache(path string, ttl time.Duration) (*ResponseCache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open LLM cache: %w", err)
+	}
+
+	c := &ResponseCache{db: db, ttl: ttl}
+	if err := c.prune(time.Now()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ResponseCache) Close() error {
+	return c.db.Close()
+}
+
+// cacheKey 缓存键，提示词中包含邮件的发件人、主题、日期、正文和日历邀请
+func cacheKey(config LLMConfig, prompt string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00", strings.ToLower(config.Provider), config.Model, promptVersion)
+	h.Write([]byte(prompt))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get 读取未过期的缓存，没有时返回 nil
+func (c *ResponseCache) get(key string) (*cachedResponse, error) {
+	var cached *cachedResponse
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(responsesBucket)
+		if b == nil {
+			return nil
+		}
+		data := b.Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		var r cachedResponse
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil // 无法解析的条目当作不存在，之后会被覆盖
+		}
+		if !c.expired(r, time.Now()) {
+			cached = &r
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read LLM cache: %w", err)
+	}
+	return cached, nil
+}
+
+func (c *ResponseCache) put(key string, r cachedResponse) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshal LLM cache: %w", err)
+	}
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(responsesBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), data)
+	})
+	if err != nil {
+		return fmt.Errorf("write LLM cache: %w", err)
+	}
+	return nil
+}
+
+func (c *ResponseCache) expired(r cachedResponse, now time.Time) bool {
+	return c.ttl > 0 && now.Sub(r.CreatedAt) > c.ttl
+}
+
+// prune 删除过期和无法解析的条目
+func (c *ResponseCache) prune(now time.Time) error {
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(responsesBucket)
+		if err != nil {
+			return err
+		}
+
+		var stale [][]byte
+		err = b.ForEach(func(k, data []byte) error {
+			var r cachedResponse
+			if json.Unmarshal(data, &r) != nil || c.expired(r, now) {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("prune LLM cache: %w", err)
+	}
+	return nil
+}
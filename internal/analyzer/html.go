@@ -0,0 +1,313 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// HTML正文转纯文本：保留链接地址、表格单元格和列表结构，去掉样式、脚本和跟踪像素。
+// 招聘系统（Workday、Greenhouse、Moka、北森等）的邮件常常只有HTML正文
+
+var (
+	// 纯文本正文只是"在浏览器中查看"之类的提示时，改用HTML正文
+	textStubPattern = regexp.MustCompile(`(?i)(view (this |it |the )?(e-?mail |message |newsletter )?(in|on) (your |a )?(web )?browser|web version|` +
+		`having trouble (viewing|reading)|can'?t (see|view|read) this (e-?mail|message)|(does not|doesn'?t) support html|` +
+		`浏览器中?(查看|打开|阅读)|网页版|无法正常显示|不支持\s*html|html\s*格式)`)
+	hiddenStylePattern = regexp.MustCompile(`(?i)display\s*:\s*none|visibility\s*:\s*hidden|max-height\s*:\s*0`)
+	pixelSizePattern   = regexp.MustCompile(`(?i)(^|[;\s])(width|height)\s*:\s*[01](px)?\s*(;|$)`)
+)
+
+// 纯文本正文超过该长度时不视为占位提示
+const maxStubRunes = 300
+
+// emailText 分析用的正文：优先使用纯文本正文，为空或只是"在浏览器中查看"的占位提示时转换HTML正文
+func emailText(email types.Email) string {
+	text := strings.TrimSpace(email.BodyText)
+	if strings.TrimSpace(email.BodyHTML) == "" {
+		return text
+	}
+	if text == "" || (utf8.RuneCountInString(text) <= maxStubRunes && textStubPattern.MatchString(text)) {
+		if converted := htmlToText(email.BodyHTML); converted != "" {
+			return converted
+		}
+	}
+	return text
+}
+
+// 内容整个丢弃的元素
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "head": true, "title": true, "noscript": true,
+	"template": true, "svg": true, "object": true, "iframe": true,
+}
+
+// 前后换行的块级元素；paragraphElements 前后空一行
+var blockElements = map[string]bool{
+	"div": true, "tr": true, "li": true, "dt": true, "dd": true, "section": true, "article": true,
+	"header": true, "footer": true, "center": true, "form": true, "address": true, "hr": true,
+	"tbody": true, "thead": true, "tfoot": true, "caption": true,
+}
+
+var paragraphElements = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"table": true, "ul": true, "ol": true, "dl": true, "blockquote": true, "pre": true,
+}
+
+// htmlToText 把HTML转换为可读的纯文本。按HTML5规则解析，标签不闭合或嵌套错误时与浏览器一样补全
+func htmlToText(src string) string {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return ""
+	}
+	c := &htmlConverter{}
+	c.walk(doc)
+	return c.String()
+}
+
+// htmlTag 一个元素的名称和属性
+type htmlTag struct {
+	name  string
+	attrs map[string]string
+}
+
+// htmlConverter 按行输出文本
+type htmlConverter struct {
+	lines  []string
+	line   strings.Builder
+	prefix string // 当前行的列表标记
+	space  bool   // 待输出的空白
+
+	pre int
+
+	lists []int // 列表栈：无序列表为 -1，有序列表为当前序号
+	cells []int // 表格栈：当前行已输出的单元格数
+	links []linkState
+}
+
+type linkState struct {
+	href  string
+	start int // 链接文字在 line 中的起始位置
+	lines int // 链接开始时的行数
+}
+
+// walk 按文档顺序输出节点。隐藏的元素连同其中的内容一起丢弃，
+// 解析器已经补全了省略的结束标签，所以隐藏范围不会超出该元素
+func (c *htmlConverter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(n.Data)
+		return
+	case html.ElementNode:
+		t := htmlTag{name: n.Data, attrs: make(map[string]string, len(n.Attr))}
+		for _, a := range n.Attr {
+			t.attrs[a.Key] = a.Val
+		}
+		if skippedElements[t.name] || hiddenStylePattern.MatchString(t.attrs["style"]) {
+			return
+		}
+		c.openTag(t)
+		defer c.closeTag(t.name)
+	case html.CommentNode, html.DoctypeNode:
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child)
+	}
+}
+
+func (c *htmlConverter) openTag(t htmlTag) {
+	switch {
+	case t.name == "br":
+		c.newline(true)
+	case t.name == "img":
+		c.image(t)
+	case t.name == "a":
+		c.links = append(c.links, linkState{href: strings.TrimSpace(t.attrs["href"]), start: c.line.Len(), lines: len(c.lines)})
+	case t.name == "ul" || t.name == "ol":
+		c.paragraph()
+		if t.name == "ol" {
+			n, _ := strconv.Atoi(t.attrs["start"])
+			c.lists = append(c.lists, max(n, 1)-1)
+		} else {
+			c.lists = append(c.lists, -1)
+		}
+	case t.name == "li":
+		c.newline(false)
+		indent := strings.Repeat("  ", max(len(c.lists)-1, 0))
+		marker := "- "
+		if n := len(c.lists); n > 0 && c.lists[n-1] >= 0 {
+			c.lists[n-1]++
+			marker = strconv.Itoa(c.lists[n-1]) + ". "
+		}
+		c.prefix = indent + marker
+	case t.name == "table":
+		c.paragraph()
+		c.cells = append(c.cells, 0)
+	case t.name == "tr":
+		c.newline(false)
+		if n := len(c.cells); n > 0 {
+			c.cells[n-1] = 0
+		}
+	case t.name == "td" || t.name == "th":
+		if n := len(c.cells); n > 0 {
+			if c.cells[n-1] > 0 && strings.TrimSpace(c.line.String()) != "" {
+				c.raw(" | ")
+			}
+			c.cells[n-1]++
+		}
+	case t.name == "pre":
+		c.paragraph()
+		c.pre++
+	case paragraphElements[t.name]:
+		c.paragraph()
+	case blockElements[t.name]:
+		c.newline(false)
+	}
+}
+
+func (c *htmlConverter) closeTag(name string) {
+	switch {
+	case name == "a":
+		if n := len(c.links); n > 0 {
+			c.endLink(c.links[n-1])
+			c.links = c.links[:n-1]
+		}
+	case name == "ul" || name == "ol":
+		if n := len(c.lists); n > 0 {
+			c.lists = c.lists[:n-1]
+		}
+		c.paragraph()
+	case name == "table":
+		if n := len(c.cells); n > 0 {
+			c.cells = c.cells[:n-1]
+		}
+		c.paragraph()
+	case name == "pre":
+		c.newline(false) // 在 <pre> 内结束最后一行，保留缩进
+		if c.pre > 0 {
+			c.pre--
+		}
+		c.paragraph()
+	case paragraphElements[name]:
+		c.paragraph()
+	case blockElements[name]:
+		c.newline(false)
+	}
+}
+
+// endLink 链接文字后附上地址，文字就是地址本身、锚点和脚本链接不附加
+func (c *htmlConverter) endLink(link linkState) {
+	href := link.href
+	lower := strings.ToLower(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(lower, "javascript:") {
+		return
+	}
+	var text string
+	if len(c.lines) == link.lines && link.start <= c.line.Len() {
+		text = strings.TrimSpace(c.line.String()[link.start:])
+	} else {
+		text = strings.TrimSpace(c.line.String()) // 链接跨行时只看当前行
+	}
+	if text == "" {
+		return // 图片链接（如logo）没有文字，不输出地址
+	}
+	if text == href || text == strings.TrimPrefix(href, "mailto:") {
+		return
+	}
+	c.raw(fmt.Sprintf(" (%s)", href))
+}
+
+// image 跟踪像素（1x1或隐藏的图片）丢弃，其他图片输出替代文字
+func (c *htmlConverter) image(t htmlTag) {
+	if isTrackingPixel(t.attrs) {
+		return
+	}
+	if alt := strings.TrimSpace(t.attrs["alt"]); alt != "" {
+		c.text("[" + alt + "]")
+	}
+}
+
+func isTrackingPixel(attrs map[string]string) bool {
+	for _, name := range []string{"width", "height"} {
+		if v := strings.TrimSuffix(strings.TrimSpace(attrs[name]), "px"); v == "0" || v == "1" {
+			return true
+		}
+	}
+	style := attrs["style"]
+	return hiddenStylePattern.MatchString(style) || pixelSizePattern.MatchString(style)
+}
+
+// text 输出文本（实体已由解析器解码），连续空白合并为一个空格（<pre> 中保留原样）
+func (c *htmlConverter) text(s string) {
+	if c.pre > 0 {
+		for i, part := range strings.Split(s, "\n") {
+			if i > 0 {
+				c.newline(true)
+			}
+			c.raw(part)
+		}
+		return
+	}
+
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '\u200b' || r == '\u200c' || r == '\u034f' {
+			// 零宽字符常被用来填充预览文字，按空白处理
+			c.space = c.line.Len() > 0
+			continue
+		}
+		if c.space {
+			c.line.WriteByte(' ')
+			c.space = false
+		}
+		c.line.WriteRune(r)
+	}
+}
+
+// raw 原样输出，不合并空白
+func (c *htmlConverter) raw(s string) {
+	if c.space {
+		c.line.WriteByte(' ')
+		c.space = false
+	}
+	c.line.WriteString(s)
+}
+
+// newline 结束当前行。force 为 true 时（<br>）空行也保留
+func (c *htmlConverter) newline(force bool) {
+	s := strings.TrimRightFunc(c.line.String(), unicode.IsSpace)
+	if c.pre == 0 {
+		s = strings.TrimSpace(s)
+	}
+	switch {
+	case s != "":
+		c.lines = append(c.lines, c.prefix+s)
+		c.prefix = "" // 列表标记只加在列表项的第一行
+	case force && len(c.lines) > 0 && c.lines[len(c.lines)-1] != "":
+		c.lines = append(c.lines, "")
+	}
+	c.line.Reset()
+	c.space = false
+}
+
+// paragraph 结束当前行并空一行，列表中只换行
+func (c *htmlConverter) paragraph() {
+	c.newline(false)
+	if len(c.lists) > 0 {
+		return
+	}
+	if n := len(c.lines); n > 0 && c.lines[n-1] != "" {
+		c.lines = append(c.lines, "")
+	}
+}
+
+func (c *htmlConverter) String() string {
+	c.newline(false)
+	return strings.TrimSpace(strings.Join(c.lines, "\n"))
+}
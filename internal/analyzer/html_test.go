@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "paragraphs and entities",
+			html: `<html><head><title>Acme</title><style>p{color:red}</style></head>` +
+				`<body><p>Hi&nbsp;Alex,</p><p>Your interview is on <b>Mar 15</b> &amp; lasts 1h. &lt;3</p></body></html>`,
+			want: "Hi Alex,\n\nYour interview is on Mar 15 & lasts 1h. <3",
+		},
+		{
+			name: "links",
+			html: `<p>Join via <a href="https://zoom.us/j/123">Zoom</a> or <a href="https://example.com">https://example.com</a>.` +
+				` <a href="#top">Top</a> <a href="mailto:hr@acme.com">hr@acme.com</a></p>`,
+			want: "Join via Zoom (https://zoom.us/j/123) or https://example.com. Top hr@acme.com",
+		},
+		{
+			name: "lists",
+			html: `<ul><li>Resume</li><li>Portfolio<ol start="3"><li>Case study</li><li>Code sample</li></ol></li></ul><p>Thanks</p>`,
+			want: "- Resume\n- Portfolio\n  3. Case study\n  4. Code sample\n\nThanks",
+		},
+		{
+			name: "table cells",
+			html: `<table><tr><th>Stage</th><th>Time</th></tr><tr><td>Onsite</td><td>10:00</td></tr></table>`,
+			want: "Stage | Time\nOnsite | 10:00",
+		},
+		{
+			name: "tracking pixel and images",
+			html: `<p><img src="logo.png" alt="Acme"> Offer letter<img src="t.gif" width="1" height="1" alt="pixel"></p>`,
+			want: "[Acme] Offer letter",
+		},
+		{
+			name: "pre keeps indentation",
+			html: "<pre>def f():\n    return 1</pre><p>done</p>",
+			want: "def f():\n    return 1\n\ndone",
+		},
+		{
+			name: "hidden element",
+			html: `<div style="display:none">preview text<div>nested</div></div><p>Visible</p>`,
+			want: "Visible",
+		},
+		{
+			name: "hidden paragraph without closing tag",
+			html: `<p style="display: none">preview text<p>Interview on Monday</p>`,
+			want: "Interview on Monday",
+		},
+		{
+			name: "hidden cell without closing tag ends with its row",
+			html: `<table><tr><td style="visibility:hidden">spacer<tr><td>Offer details</td></table><p>Regards</p>`,
+			want: "Offer details\n\nRegards",
+		},
+		{
+			name: "hidden element ends with its parent",
+			html: `<div><span style="display:none">preheader</div><p>Please confirm your availability</p>`,
+			want: "Please confirm your availability",
+		},
+		{
+			name: "malformed markup",
+			html: `<p>Round 2 <b>interview<p>Next steps`,
+			want: "Round 2 interview\n\nNext steps",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(tt.html); got != tt.want {
+				t.Errorf("htmlToText() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailTextPrefersPlainBody(t *testing.T) {
+	html := `<p>Interview invitation for <b>Backend Engineer</b></p>`
+
+	email := types.Email{BodyText: "Plain text body with the details.", BodyHTML: html}
+	if got := emailText(email); got != email.BodyText {
+		t.Errorf("emailText = %q, want plain body", got)
+	}
+
+	for _, stub := range []string{"", "View this email in your browser", "如果邮件无法正常显示，请点击这里"} {
+		email := types.Email{BodyText: stub, BodyHTML: html}
+		if got := emailText(email); !strings.Contains(got, "Backend Engineer") {
+			t.Errorf("emailText with stub %q = %q, want converted HTML", stub, got)
+		}
+	}
+}
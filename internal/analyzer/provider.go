@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 支持的LLM后端（llm.provider）
+const (
+	ProviderOpenAI    = "openai"    // OpenAI兼容接口（OpenAI、DeepSeek等）
+	ProviderAnthropic = "anthropic" // Anthropic Messages API
+	ProviderOllama    = "ollama"    // 本地 Ollama 服务
+)
+
+// Provider LLM后端，只负责把一次对话请求发送给具体的API
+type Provider interface {
+	Complete(ctx context.Context, req CompletionRequest) (Completion, error)
+}
+
+// Completion 模型的回复和本次请求的token用量（后端没有返回用量时为零值）
+type Completion struct {
+	Content string
+	Usage   Usage
+}
+
+// 一次对话请求
+type CompletionRequest struct {
+	Messages    []Message
+	Temperature float64
+	MaxTokens   int
+
+	// 结构化输出模式（FormatJSONSchema/FormatJSONObject/FormatText），
+	// 后端不支持时返回 400 的 APIError，由调用方降级
+	Format     string
+	SchemaName string
+	Schema     map[string]interface{}
+}
+
+// APIError 后端返回的非200响应
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("LLM API error (%d): %s", e.StatusCode, e.Body)
+}
+
+// NewProvider 根据配置创建后端，APIBase 为空时使用各后端的默认地址
+func NewProvider(config LLMConfig, httpClient *http.Client) (Provider, error) {
+	switch strings.ToLower(config.Provider) {
+	case "", ProviderOpenAI:
+		return &openAIProvider{
+			baseURL:    baseURLOrDefault(config.APIBase, "https://api.openai.com/v1"),
+			apiKey:     config.APIKey,
+			model:      config.Model,
+			httpClient: httpClient,
+		}, nil
+	case ProviderAnthropic:
+		return &anthropicProvider{
+			baseURL:    baseURLOrDefault(config.APIBase, "https://api.anthropic.com"),
+			apiKey:     config.APIKey,
+			model:      config.Model,
+			httpClient: httpClient,
+		}, nil
+	case ProviderOllama:
+		return &ollamaProvider{
+			baseURL:    baseURLOrDefault(config.APIBase, "http://localhost:11434"),
+			model:      config.Model,
+			httpClient: httpClient,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q (supported: openai, anthropic, ollama)", config.Provider)
+	}
+}
+
+func baseURLOrDefault(base, def string) string {
+	if base == "" {
+		return def
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// postJSON 发送JSON请求并把响应解码到 out，非200响应返回 *APIError
+func postJSON(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, in, out interface{}) error {
+	reqBody, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// Anthropic Messages API（/v1/messages）
+type anthropicProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature"`
+	System      string             `json:"system,omitempty"`
+	Messages    []Message          `json:"messages"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolPick `json:"tool_choice,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicToolPick struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text,omitempty"`
+		Input json.RawMessage `json:"input,omitempty"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// Complete 结构化输出通过强制调用一个以schema为参数的工具实现，工具参数即结果JSON；
+// JSON模式没有对应参数，依赖提示词和本地校验
+func (p *anthropicProvider) Complete(ctx context.Context, req CompletionRequest) (Completion, error) {
+	body := anthropicRequest{
+		Model:       p.model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	// Messages API 要求必须指定 max_tokens
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = 2000
+	}
+
+	// system 消息单独传递
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			body.System = strings.TrimSpace(body.System + "\n" + m.Content)
+			continue
+		}
+		body.Messages = append(body.Messages, m)
+	}
+
+	if req.Format == FormatJSONSchema {
+		body.Tools = []anthropicTool{{
+			Name:        req.SchemaName,
+			Description: "按要求的格式返回分析结果",
+			InputSchema: req.Schema,
+		}}
+		body.ToolChoice = &anthropicToolPick{Type: "tool", Name: req.SchemaName}
+	}
+
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	var resp anthropicResponse
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/v1/messages", headers, body, &resp); err != nil {
+		return Completion{}, err
+	}
+	usage := Usage{PromptTokens: resp.Usage.InputTokens, CompletionTokens: resp.Usage.OutputTokens}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "tool_use":
+			return Completion{Content: string(block.Input), Usage: usage}, nil
+		case "text":
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return Completion{}, fmt.Errorf("no response from LLM")
+	}
+	return Completion{Content: text.String(), Usage: usage}, nil
+}
@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// 本地 Ollama 服务（/api/chat），不需要API密钥
+type ollamaProvider struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   interface{}   `json:"format,omitempty"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// Complete format 参数可以是 "json"（JSON模式）或JSON Schema对象（结构化输出）
+func (p *ollamaProvider) Complete(ctx context.Context, req CompletionRequest) (Completion, error) {
+	body := ollamaRequest{
+		Model:    p.model,
+		Messages: req.Messages,
+		Options: ollamaOptions{
+			Temperature: req.Temperature,
+			NumPredict:  req.MaxTokens,
+		},
+	}
+
+	switch req.Format {
+	case FormatJSONSchema:
+		body.Format = req.Schema
+	case FormatJSONObject:
+		body.Format = "json"
+	}
+
+	var resp ollamaResponse
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/api/chat", nil, body, &resp); err != nil {
+		return Completion{}, err
+	}
+
+	if resp.Message.Content == "" {
+		return Completion{}, fmt.Errorf("no response from LLM")
+	}
+	return Completion{
+		Content: resp.Message.Content,
+		Usage:   Usage{PromptTokens: resp.PromptEvalCount, CompletionTokens: resp.EvalCount},
+	}, nil
+}
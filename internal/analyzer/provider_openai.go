@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// OpenAI兼容的 /chat/completions 接口
+type openAIProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// LLM请求和响应结构
+type LLMRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature"`
+	MaxTokens   int       `json:"max_tokens"`
+
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// OpenAI兼容的 response_format 参数
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+type JSONSchemaFormat struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+type LLMResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (p *openAIProvider) Complete(ctx context.Context, req CompletionRequest) (Completion, error) {
+	body := LLMRequest{
+		Model:       p.model,
+		Messages:    req.Messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+
+	switch req.Format {
+	case FormatJSONSchema:
+		body.ResponseFormat = &ResponseFormat{
+			Type: FormatJSONSchema,
+			JSONSchema: &JSONSchemaFormat{
+				Name:   req.SchemaName,
+				Strict: true,
+				Schema: req.Schema,
+			},
+		}
+	case FormatJSONObject:
+		body.ResponseFormat = &ResponseFormat{Type: FormatJSONObject}
+	}
+
+	headers := map[string]string{"Authorization": "Bearer " + p.apiKey}
+
+	var resp LLMResponse
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/chat/completions", headers, body, &resp); err != nil {
+		return Completion{}, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return Completion{}, fmt.Errorf("no response from LLM")
+	}
+	return Completion{
+		Content: resp.Choices[0].Message.Content,
+		Usage:   Usage{PromptTokens: resp.Usage.PromptTokens, CompletionTokens: resp.Usage.CompletionTokens},
+	}, nil
+}
@@ -0,0 +1,483 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// 合法的分析结果
+const validAnalysis = `{"is_job_related":true,"confidence":0.9,"company":"Acme","position":"Backend Engineer",` +
+	`"requisition_id":"","status":"INTERVIEW","location":"","description":"面试邀请","event_start":"2024-03-15T14:00:00+08:00",` +
+	`"event_end":"","deadline":"","timezone":"Asia/Shanghai","meeting_url":""}`
+
+// recordedRequest 模拟服务收到的一次请求
+type recordedRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// fakeLLM 本地模拟的LLM服务，按顺序返回预设的响应，最后一个响应重复使用
+type fakeLLM struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	requests  []recordedRequest
+	responses []fakeResponse
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+func newFakeLLM(t *testing.T, responses ...fakeResponse) *fakeLLM {
+	t.Helper()
+	f := &fakeLLM{responses: responses}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeLLM) handle(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+	var body map[string]interface{}
+	json.Unmarshal(data, &body)
+
+	f.mu.Lock()
+	f.requests = append(f.requests, recordedRequest{Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+	resp := f.responses[0]
+	if len(f.responses) > 1 {
+		f.responses = f.responses[1:]
+	}
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.status)
+	io.WriteString(w, resp.body)
+}
+
+func (f *fakeLLM) recorded() []recordedRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]recordedRequest(nil), f.requests...)
+}
+
+func okResponse(body string) fakeResponse {
+	return fakeResponse{status: http.StatusOK, body: body}
+}
+
+func openAIReply(content string) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{"message": map[string]string{"role": "assistant", "content": content}}},
+		"usage":   map[string]int{"prompt_tokens": 120, "completion_tokens": 30},
+	})
+	return string(data)
+}
+
+func newTestProvider(t *testing.T, provider, baseURL string) Provider {
+	t.Helper()
+	p, err := NewProvider(LLMConfig{Provider: provider, APIBase: baseURL + "/", APIKey: "test-key", Model: "test-model"},
+		&http.Client{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func schemaRequest() CompletionRequest {
+	return CompletionRequest{
+		Messages: []Message{
+			{Role: "system", Content: "你是招聘邮件分析助手"},
+			{Role: "user", Content: "分析这封邮件"},
+		},
+		Temperature: 0.1,
+		MaxTokens:   500,
+		Format:      FormatJSONSchema,
+		SchemaName:  "job_email_analysis",
+		Schema:      analysisSchema,
+	}
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	llm := newFakeLLM(t, okResponse(openAIReply(validAnalysis)))
+	p := newTestProvider(t, ProviderOpenAI, llm.URL)
+
+	completion, err := p.Complete(context.Background(), schemaRequest())
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if completion.Content != validAnalysis {
+		t.Errorf("content = %q", completion.Content)
+	}
+	if completion.Usage != (Usage{PromptTokens: 120, CompletionTokens: 30}) {
+		t.Errorf("usage = %+v", completion.Usage)
+	}
+
+	req := llm.recorded()[0]
+	if req.Path != "/chat/completions" {
+		t.Errorf("path = %q", req.Path)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer test-key" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if req.Body["model"] != "test-model" || req.Body["max_tokens"] != 500.0 {
+		t.Errorf("body = %v", req.Body)
+	}
+	if msgs := req.Body["messages"].([]interface{}); len(msgs) != 2 {
+		t.Errorf("messages = %v, want system and user", msgs)
+	}
+	format, _ := req.Body["response_format"].(map[string]interface{})
+	schema, _ := format["json_schema"].(map[string]interface{})
+	if format["type"] != FormatJSONSchema || schema["name"] != "job_email_analysis" || schema["strict"] != true || schema["schema"] == nil {
+		t.Errorf("response_format = %v", req.Body["response_format"])
+	}
+}
+
+func TestOpenAIProviderFormats(t *testing.T) {
+	llm := newFakeLLM(t, okResponse(openAIReply("{}")))
+	p := newTestProvider(t, ProviderOpenAI, llm.URL)
+
+	req := schemaRequest()
+	req.Format = FormatJSONObject
+	if _, err := p.Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Format = FormatText
+	if _, err := p.Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := llm.recorded()
+	if format, _ := recorded[0].Body["response_format"].(map[string]interface{}); format["type"] != FormatJSONObject || format["json_schema"] != nil {
+		t.Errorf("json_object response_format = %v", recorded[0].Body["response_format"])
+	}
+	if _, ok := recorded[1].Body["response_format"]; ok {
+		t.Errorf("text request has response_format %v", recorded[1].Body["response_format"])
+	}
+}
+
+func TestOpenAIProviderErrors(t *testing.T) {
+	llm := newFakeLLM(t,
+		fakeResponse{status: http.StatusUnauthorized, body: `{"error":{"message":"invalid api key"}}`},
+		okResponse(`{"choices":[]}`),
+		okResponse(`not json`),
+	)
+	p := newTestProvider(t, ProviderOpenAI, llm.URL)
+
+	_, err := p.Complete(context.Background(), schemaRequest())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || !strings.Contains(apiErr.Body, "invalid api key") {
+		t.Errorf("err = %v, want APIError 401", err)
+	}
+	if _, err := p.Complete(context.Background(), schemaRequest()); err == nil {
+		t.Error("empty choices: expected error")
+	}
+	if _, err := p.Complete(context.Background(), schemaRequest()); err == nil {
+		t.Error("malformed response: expected error")
+	}
+}
+
+func TestAnthropicProviderToolUse(t *testing.T) {
+	llm := newFakeLLM(t, okResponse(`{"content":[{"type":"text","text":"分析如下"},`+
+		`{"type":"tool_use","id":"toolu_1","name":"job_email_analysis","input":`+validAnalysis+`}],`+
+		`"usage":{"input_tokens":200,"output_tokens":40}}`))
+	p := newTestProvider(t, ProviderAnthropic, llm.URL)
+
+	completion, err := p.Complete(context.Background(), schemaRequest())
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if _, err := parseAnalysis(completion.Content, time.UTC); err != nil {
+		t.Errorf("tool input is not a valid analysis: %v (%q)", err, completion.Content)
+	}
+	if completion.Usage != (Usage{PromptTokens: 200, CompletionTokens: 40}) {
+		t.Errorf("usage = %+v", completion.Usage)
+	}
+
+	req := llm.recorded()[0]
+	if req.Path != "/v1/messages" {
+		t.Errorf("path = %q", req.Path)
+	}
+	if req.Header.Get("x-api-key") != "test-key" || req.Header.Get("anthropic-version") != anthropicVersion {
+		t.Errorf("headers = %v", req.Header)
+	}
+	// system 消息单独传递
+	if req.Body["system"] != "你是招聘邮件分析助手" {
+		t.Errorf("system = %v", req.Body["system"])
+	}
+	msgs := req.Body["messages"].([]interface{})
+	if len(msgs) != 1 || msgs[0].(map[string]interface{})["role"] != "user" {
+		t.Errorf("messages = %v", msgs)
+	}
+	tools := req.Body["tools"].([]interface{})
+	tool := tools[0].(map[string]interface{})
+	if len(tools) != 1 || tool["name"] != "job_email_analysis" || tool["input_schema"] == nil {
+		t.Errorf("tools = %v", tools)
+	}
+	choice := req.Body["tool_choice"].(map[string]interface{})
+	if choice["type"] != "tool" || choice["name"] != "job_email_analysis" {
+		t.Errorf("tool_choice = %v", choice)
+	}
+}
+
+func TestAnthropicProviderText(t *testing.T) {
+	llm := newFakeLLM(t,
+		okResponse(`{"content":[{"type":"text","text":"{\"a\":"},{"type":"text","text":"1}"}],"usage":{"input_tokens":10,"output_tokens":5}}`),
+		okResponse(`{"content":[]}`),
+		fakeResponse{status: http.StatusTooManyRequests, body: `{"type":"error","error":{"type":"rate_limit_error"}}`},
+	)
+	p := newTestProvider(t, ProviderAnthropic, llm.URL)
+
+	req := schemaRequest()
+	req.Format = FormatJSONObject
+	req.MaxTokens = 0
+	completion, err := p.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completion.Content != `{"a":1}` {
+		t.Errorf("content = %q, want text blocks joined", completion.Content)
+	}
+	body := llm.recorded()[0].Body
+	if _, ok := body["tools"]; ok {
+		t.Error("json_object request should not use tools")
+	}
+	if body["max_tokens"] != 2000.0 {
+		t.Errorf("max_tokens = %v, want default 2000", body["max_tokens"])
+	}
+
+	if _, err := p.Complete(context.Background(), req); err == nil {
+		t.Error("empty content: expected error")
+	}
+	var apiErr *APIError
+	if _, err := p.Complete(context.Background(), req); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("err = %v, want APIError 429", err)
+	}
+}
+
+func TestOllamaProvider(t *testing.T) {
+	reply := `{"message":{"role":"assistant","content":` + mustJSON(validAnalysis) + `},"done":true,"prompt_eval_count":80,"eval_count":25}`
+	llm := newFakeLLM(t, okResponse(reply))
+	p := newTestProvider(t, ProviderOllama, llm.URL)
+
+	completion, err := p.Complete(context.Background(), schemaRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completion.Content != validAnalysis || completion.Usage != (Usage{PromptTokens: 80, CompletionTokens: 25}) {
+		t.Errorf("completion = %+v", completion)
+	}
+
+	req := schemaRequest()
+	req.Format = FormatJSONObject
+	if _, err := p.Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Format = FormatText
+	if _, err := p.Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := llm.recorded()
+	first := recorded[0]
+	if first.Path != "/api/chat" || first.Header.Get("Authorization") != "" {
+		t.Errorf("path = %q, Authorization = %q", first.Path, first.Header.Get("Authorization"))
+	}
+	if first.Body["stream"] != false {
+		t.Errorf("stream = %v, want false", first.Body["stream"])
+	}
+	if schema, _ := first.Body["format"].(map[string]interface{}); schema["type"] != "object" {
+		t.Errorf("json_schema format = %v", first.Body["format"])
+	}
+	if options := first.Body["options"].(map[string]interface{}); options["num_predict"] != 500.0 {
+		t.Errorf("options = %v", options)
+	}
+	if recorded[1].Body["format"] != "json" {
+		t.Errorf("json_object format = %v", recorded[1].Body["format"])
+	}
+	if _, ok := recorded[2].Body["format"]; ok {
+		t.Errorf("text request has format %v", recorded[2].Body["format"])
+	}
+}
+
+func TestOllamaProviderErrors(t *testing.T) {
+	llm := newFakeLLM(t,
+		fakeResponse{status: http.StatusNotFound, body: `{"error":"model \"test-model\" not found, try pulling it first"}`},
+		okResponse(`{"message":{"role":"assistant","content":""},"done":true}`),
+	)
+	p := newTestProvider(t, ProviderOllama, llm.URL)
+
+	var apiErr *APIError
+	if _, err := p.Complete(context.Background(), schemaRequest()); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("err = %v, want APIError 404", err)
+	}
+	if _, err := p.Complete(context.Background(), schemaRequest()); err == nil {
+		t.Error("empty content: expected error")
+	}
+}
+
+func TestNewProviderUnknown(t *testing.T) {
+	if _, err := NewProvider(LLMConfig{Provider: "gemini"}, http.DefaultClient); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+// 以下测试经过 JobAnalyzer，覆盖降级重试和修复重试
+
+func testEmail() types.Email {
+	return types.Email{
+		MessageID: "<test@example.com>",
+		From:      "hr@acme.com",
+		Subject:   "面试邀请",
+		Date:      time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC),
+		BodyText:  "您好，邀请您参加 Backend Engineer 岗位的面试。",
+	}
+}
+
+func newTestAnalyzer(t *testing.T, provider, baseURL string) *JobAnalyzer {
+	t.Helper()
+	return NewJobAnalyzerWithProvider(LLMConfig{Provider: provider, Model: "test-model", MaxTokens: 500},
+		newTestProvider(t, provider, baseURL))
+}
+
+func TestClassifyEmailDowngradesFormat(t *testing.T) {
+	llm := newFakeLLM(t,
+		fakeResponse{status: http.StatusBadRequest, body: `{"error":{"message":"response_format.type 'json_schema' is not supported by this model"}}`},
+		fakeResponse{status: http.StatusBadRequest, body: `{"error":{"message":"Invalid value for response_format: json_object"}}`},
+		okResponse(openAIReply("```json\n"+validAnalysis+"\n```")),
+	)
+	ja := newTestAnalyzer(t, ProviderOpenAI, llm.URL)
+
+	classification, err := ja.ClassifyEmail(context.Background(), testEmail())
+	if err != nil {
+		t.Fatalf("ClassifyEmail: %v", err)
+	}
+	if !classification.IsJobRelated || classification.Application.Company != "Acme" ||
+		classification.Application.Status != types.StatusInterview {
+		t.Errorf("classification = %+v", classification)
+	}
+
+	var formats []interface{}
+	for _, req := range llm.recorded() {
+		format, _ := req.Body["response_format"].(map[string]interface{})
+		formats = append(formats, format["type"])
+	}
+	if len(formats) != 3 || formats[0] != FormatJSONSchema || formats[1] != FormatJSONObject || formats[2] != nil {
+		t.Errorf("requested formats = %v, want json_schema, json_object, none", formats)
+	}
+	if got := ja.currentFormat(); got != FormatText {
+		t.Errorf("format after downgrade = %q, want text", got)
+	}
+	if stats := ja.Stats(); stats.Requests != 1 {
+		t.Errorf("stats.Requests = %d, want 1 successful request", stats.Requests)
+	}
+}
+
+func TestClassifyEmailAnthropicToolChoiceUnsupported(t *testing.T) {
+	llm := newFakeLLM(t,
+		fakeResponse{status: http.StatusBadRequest, body: `{"type":"error","error":{"type":"invalid_request_error","message":"tool_choice is not supported"}}`},
+		okResponse(`{"content":[{"type":"text","text":`+mustJSON(validAnalysis)+`}],"usage":{"input_tokens":90,"output_tokens":30}}`),
+	)
+	ja := newTestAnalyzer(t, ProviderAnthropic, llm.URL)
+
+	classification, err := ja.ClassifyEmail(context.Background(), testEmail())
+	if err != nil {
+		t.Fatalf("ClassifyEmail: %v", err)
+	}
+	if classification.Usage != (Usage{PromptTokens: 90, CompletionTokens: 30}) {
+		t.Errorf("usage = %+v", classification.Usage)
+	}
+	recorded := llm.recorded()
+	if len(recorded) != 2 || recorded[0].Body["tools"] == nil || recorded[1].Body["tools"] != nil {
+		t.Errorf("expected tool request followed by plain request, got %d requests", len(recorded))
+	}
+}
+
+func TestClassifyEmailNoRetryOnOtherErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		resp fakeResponse
+	}{
+		{"bad request", fakeResponse{status: http.StatusBadRequest, body: `{"error":{"message":"maximum context length exceeded"}}`}},
+		{"server error", fakeResponse{status: http.StatusInternalServerError, body: `upstream unavailable`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := newFakeLLM(t, tt.resp)
+			ja := newTestAnalyzer(t, ProviderOpenAI, llm.URL)
+
+			_, err := ja.ClassifyEmail(context.Background(), testEmail())
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.resp.status {
+				t.Errorf("err = %v, want APIError %d", err, tt.resp.status)
+			}
+			if n := len(llm.recorded()); n != 1 {
+				t.Errorf("requests = %d, want 1", n)
+			}
+			if got := ja.currentFormat(); got != FormatJSONSchema {
+				t.Errorf("format = %q, want unchanged json_schema", got)
+			}
+		})
+	}
+}
+
+func TestClassifyEmailRepairsInvalidResponse(t *testing.T) {
+	ollamaReply := func(content string) fakeResponse {
+		return okResponse(`{"message":{"role":"assistant","content":` + mustJSON(content) + `},"done":true,"prompt_eval_count":50,"eval_count":10}`)
+	}
+	llm := newFakeLLM(t,
+		ollamaReply(`{"is_job_related":true,"confidence":0.9}`),
+		ollamaReply(validAnalysis),
+	)
+	ja := newTestAnalyzer(t, ProviderOllama, llm.URL)
+
+	classification, err := ja.ClassifyEmail(context.Background(), testEmail())
+	if err != nil {
+		t.Fatalf("ClassifyEmail: %v", err)
+	}
+	if classification.Usage != (Usage{PromptTokens: 100, CompletionTokens: 20}) {
+		t.Errorf("usage = %+v, want both attempts counted", classification.Usage)
+	}
+
+	recorded := llm.recorded()
+	if len(recorded) != 2 {
+		t.Fatalf("requests = %d, want 2", len(recorded))
+	}
+	// 修复请求带上之前的回复和错误说明
+	msgs := recorded[1].Body["messages"].([]interface{})
+	if len(msgs) != 3 {
+		t.Fatalf("repair messages = %v", msgs)
+	}
+	last := msgs[2].(map[string]interface{})["content"].(string)
+	if msgs[1].(map[string]interface{})["role"] != "assistant" || !strings.Contains(last, "missing field") {
+		t.Errorf("repair messages = %v", msgs)
+	}
+}
+
+func TestClassifyEmailGivesUpAfterRepairs(t *testing.T) {
+	llm := newFakeLLM(t, okResponse(openAIReply("抱歉，我无法判断")))
+	ja := newTestAnalyzer(t, ProviderOpenAI, llm.URL)
+
+	if _, err := ja.ClassifyEmail(context.Background(), testEmail()); err == nil {
+		t.Fatal("expected error for persistently invalid responses")
+	}
+	if n := len(llm.recorded()); n != maxRepairAttempts+1 {
+		t.Errorf("requests = %d, want %d", n, maxRepairAttempts+1)
+	}
+}
+
+func mustJSON(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 令牌桶：按每分钟的速率匀速补充，桶容量等于每分钟配额
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	perSec   float64
+	last     time.Time
+}
+
+// newTokenBucket 创建每分钟 perMinute 个令牌的桶，perMinute <= 0 表示不限速（返回 nil）
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// wait 阻塞直到取得 n 个令牌或 ctx 结束。超过桶容量的请求按容量计算，避免永远等待
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	if b == nil {
+		return nil
+	}
+
+	need := float64(n)
+	if need > b.capacity {
+		need = b.capacity
+	}
+
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.perSec
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+
+		if b.tokens >= need {
+			b.tokens -= need
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((need - b.tokens) / b.perSec * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rateLimiter 同时限制每分钟请求数和每分钟token数
+type rateLimiter struct {
+	requests *tokenBucket
+	tokens   *tokenBucket
+}
+
+func newRateLimiter(requestsPerMinute, tokensPerMinute int) *rateLimiter {
+	return &rateLimiter{
+		requests: newTokenBucket(requestsPerMinute),
+		tokens:   newTokenBucket(tokensPerMinute),
+	}
+}
+
+// wait 为一次预计消耗 tokens 个token的请求取得配额
+func (l *rateLimiter) wait(ctx context.Context, tokens int) error {
+	if err := l.requests.wait(ctx, 1); err != nil {
+		return err
+	}
+	return l.tokens.wait(ctx, tokens)
+}
+
+// estimateTokens 粗略估算文本的token数：ASCII约4个字符一个token，其他字符（如中文）约一个字符一个token
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return ascii/4 + other + 1
+}
@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 单次调用返回的结构化结果，字段与 analysisSchema 一一对应
+type emailAnalysis struct {
+	IsJobRelated  bool    `json:"is_job_related"`
+	Confidence    float64 `json:"confidence"`
+	Company       string  `json:"company"`
+	Position      string  `json:"position"`
+	RequisitionID string  `json:"requisition_id"`
+	Status        string  `json:"status"`
+	Location      string  `json:"location"`
+	Description   string  `json:"description"`
+	EventStart    string  `json:"event_start"`
+	EventEnd      string  `json:"event_end"`
+	Deadline      string  `json:"deadline"`
+	Timezone      string  `json:"timezone"`
+	MeetingURL    string  `json:"meeting_url"`
+
+	// 按 timezone 解析后的时间，未提供时为零值
+	start, end, deadline time.Time
+}
+
+var statusEnum = []string{"APPLIED", "OA", "INTERVIEW", "OFFER", "REJECTED", "WITHDRAWN", "OTHER"}
+
+// analysisSchema 结构化输出使用的JSON Schema（strict 模式要求所有字段必填且不允许额外字段）。
+// 部分服务不支持 minimum/maximum，confidence 的范围在 parseAnalysis 中校验
+var analysisSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"is_job_related": map[string]interface{}{"type": "boolean"},
+		"confidence":     map[string]interface{}{"type": "number"},
+		"company":        map[string]interface{}{"type": "string"},
+		"position":       map[string]interface{}{"type": "string"},
+		"requisition_id": map[string]interface{}{"type": "string"},
+		"status":         map[string]interface{}{"type": "string", "enum": statusEnum},
+		"location":       map[string]interface{}{"type": "string"},
+		"description":    map[string]interface{}{"type": "string"},
+		"event_start":    map[string]interface{}{"type": "string"},
+		"event_end":      map[string]interface{}{"type": "string"},
+		"deadline":       map[string]interface{}{"type": "string"},
+		"timezone":       map[string]interface{}{"type": "string"},
+		"meeting_url":    map[string]interface{}{"type": "string"},
+	},
+	"required": []string{"is_job_related", "confidence", "company", "position", "requisition_id", "status", "location", "description",
+		"event_start", "event_end", "deadline", "timezone", "meeting_url"},
+	"additionalProperties": false,
+}
+
+// parseAnalysis 从模型回复中提取JSON并按 analysisSchema 校验。
+// 没有时区信息的时间按 timezone 字段解释，timezone 也无法识别时使用 loc
+func parseAnalysis(response string, loc *time.Location) (*emailAnalysis, error) {
+	jsonStr := extractJSON(response)
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(jsonStr), &fields); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	for _, key := range analysisSchema["required"].([]string) {
+		if _, ok := fields[key]; !ok {
+			return nil, fmt.Errorf("missing field %q", key)
+		}
+	}
+
+	var analysis emailAnalysis
+	if err := json.Unmarshal([]byte(jsonStr), &analysis); err != nil {
+		return nil, fmt.Errorf("field type mismatch: %w", err)
+	}
+
+	if analysis.Confidence < 0 || analysis.Confidence > 1 {
+		return nil, fmt.Errorf("confidence must be between 0 and 1, got %v", analysis.Confidence)
+	}
+	analysis.Status = strings.ToUpper(strings.TrimSpace(analysis.Status))
+	if !containsString(statusEnum, analysis.Status) {
+		return nil, fmt.Errorf("status must be one of %s, got %q", strings.Join(statusEnum, "/"), analysis.Status)
+	}
+	if analysis.IsJobRelated && strings.TrimSpace(analysis.Company) == "" && strings.TrimSpace(analysis.Position) == "" {
+		return nil, fmt.Errorf("company or position is required when is_job_related is true")
+	}
+
+	analysis.Timezone = strings.TrimSpace(analysis.Timezone)
+	if analysis.Timezone != "" {
+		if l, err := time.LoadLocation(analysis.Timezone); err == nil {
+			loc = l
+		}
+	}
+	var err error
+	if analysis.start, err = parseEventTime(analysis.EventStart, loc); err != nil {
+		return nil, fmt.Errorf("event_start: %w", err)
+	}
+	if analysis.end, err = parseEventTime(analysis.EventEnd, loc); err != nil {
+		return nil, fmt.Errorf("event_end: %w", err)
+	}
+	if analysis.deadline, err = parseEventTime(analysis.Deadline, loc); err != nil {
+		return nil, fmt.Errorf("deadline: %w", err)
+	}
+	if !analysis.end.IsZero() && analysis.end.Before(analysis.start) {
+		return nil, fmt.Errorf("event_end %q is before event_start %q", analysis.EventEnd, analysis.EventStart)
+	}
+
+	return &analysis, nil
+}
+
+// 模型返回时间可接受的格式，优先 RFC3339
+var eventTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseEventTime 解析模型返回的时间，空字符串表示没有
+func parseEventTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range eventTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("time must be RFC3339 (e.g. 2024-03-15T14:00:00+08:00) or YYYY-MM-DD, got %q", value)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 邮件正文清理：去掉引用的历史邮件、签名和免责声明，再按token预算截断或分段
+
+var (
+	// 回复时引用原邮件的分隔行，之后的内容都是历史邮件
+	quoteHeaderPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^On\s.+\swrote:$`),
+		regexp.MustCompile(`^(在|于).+写道[:：]$`),
+		regexp.MustCompile(`^.+于.+写道[:：]$`),
+		regexp.MustCompile(`(?i)^-{2,}\s*(原始邮件|original message|reply message|回复的邮件)\s*-{2,}$`),
+	}
+	// Outlook 风格的引用头：发件人行之后几行内有发送时间行
+	outlookFromPattern = regexp.MustCompile(`(?i)^\*?(from|发件人)\s*[:：]`)
+	outlookSentPattern = regexp.MustCompile(`(?i)^\*?(sent|date|发送时间|时间|日期)\s*[:：]`)
+
+	// 移动端的默认签名，单独一行
+	mobileSignaturePattern = regexp.MustCompile(`(?i)^(sent from my \w+|get outlook for \w+|发自我的\s*\w+)$`)
+
+	// 免责声明、保密声明和退订说明所在的段落，只在正文末尾去掉
+	disclaimerPattern = regexp.MustCompile(`(?i)(confidentiality notice|disclaimer|免责声明|` +
+		`this (e-?mail|message|communication)( and any (attachments|files)[^.]*)? (is|are|may contain|contains) (strictly )?(confidential|privileged)|` +
+		`(is|are) intended (only |solely |exclusively )?for the (sole |exclusive )?(use of the )?(named )?(addressee|recipient|individual|person|entity)|` +
+		`if you (are not the intended recipient|have received this (e-?mail|message|communication) in error)|` +
+		`本邮件(及其)?(任何)?附件.{0,20}(保密|机密)|如果您不是(本邮件的)?(预期|指定)?收件人|` +
+		`to unsubscribe|unsubscribe from|取消订阅|退订)`)
+
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// cleanBody 去掉引用的历史邮件、签名和免责声明。清理后为空（如整封都是引用）时返回原文
+func cleanBody(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	cleaned := stripDisclaimers(stripSignature(stripQuoted(text)))
+	cleaned = strings.TrimSpace(blankLines.ReplaceAllString(cleaned, "\n\n"))
+	if cleaned == "" {
+		return strings.TrimSpace(text)
+	}
+	return cleaned
+}
+
+// stripQuoted 去掉 ">" 开头的引用行，遇到引用头（"On … wrote:"、"-----原始邮件-----" 等）时丢弃之后的全部内容
+func stripQuoted(text string) string {
+	lines := strings.Split(text, "\n")
+	var kept []string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if isQuoteHeader(lines, i) {
+			break
+		}
+		// "On … <addr>" 和 "wrote:" 被折成两行
+		if strings.HasPrefix(trimmed, "On ") && i+1 < len(lines) && strings.EqualFold(strings.TrimSpace(lines[i+1]), "wrote:") {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+func isQuoteHeader(lines []string, i int) bool {
+	line := strings.TrimSpace(lines[i])
+	for _, p := range quoteHeaderPatterns {
+		if p.MatchString(line) {
+			return true
+		}
+	}
+	if !outlookFromPattern.MatchString(line) {
+		return false
+	}
+	for j := i + 1; j < len(lines) && j <= i+4; j++ {
+		if outlookSentPattern.MatchString(strings.TrimSpace(lines[j])) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripSignature 去掉签名分隔线（"-- "）之后的内容和移动端默认签名
+func stripSignature(text string) string {
+	lines := strings.Split(text, "\n")
+	var kept []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "--" {
+			break
+		}
+		if mobileSignaturePattern.MatchString(trimmed) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// stripDisclaimers 从末尾开始去掉连续的免责声明或退订说明段落（邮件页脚），
+// 遇到第一个普通段落即停止，正文中间提到这些字样的段落保留
+func stripDisclaimers(text string) string {
+	paragraphs := strings.Split(text, "\n\n")
+	end := len(paragraphs)
+	for end > 0 {
+		p := strings.TrimSpace(paragraphs[end-1])
+		if p != "" && !disclaimerPattern.MatchString(p) {
+			break
+		}
+		end--
+	}
+	return strings.Join(paragraphs[:end], "\n\n")
+}
+
+// runeTokens 单个字符的估算token数，与 estimateTokens 一致：ASCII约4个字符一个token，其他字符约一个
+func runeTokens(r rune) float64 {
+	if r < utf8.RuneSelf {
+		return 0.25
+	}
+	return 1
+}
+
+// truncateTokens 按估算token数截断，不会截断多字节字符，截断时在末尾加 "..."
+func truncateTokens(text string, budget int) string {
+	if estimateTokens(text) <= budget {
+		return text
+	}
+	end := cutIndex(text, budget)
+	return strings.TrimRightFunc(text[:end], func(r rune) bool { return r == ' ' || r == '\n' || r == '\t' }) + "..."
+}
+
+// cutIndex 不超过 budget 个token的最长前缀的字节长度（至少一个字符，保证分段能推进）
+func cutIndex(text string, budget int) int {
+	used := 0.0
+	for i, r := range text {
+		used += runeTokens(r)
+		if used > float64(budget) && i > 0 {
+			return i
+		}
+	}
+	return len(text)
+}
+
+// splitChunks 把文本按行分成每段不超过 budget 个token的若干段，单行过长时在字符边界处切开
+func splitChunks(text string, budget int) []string {
+	if estimateTokens(text) <= budget {
+		return []string{text}
+	}
+
+	var (
+		chunks []string
+		cur    strings.Builder
+		used   int
+	)
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		cur.Reset()
+		used = 0
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for estimateTokens(line) > budget {
+			flush()
+			end := cutIndex(line, budget)
+			if s := strings.TrimSpace(line[:end]); s != "" {
+				chunks = append(chunks, s)
+			}
+			line = line[end:]
+		}
+		n := estimateTokens(line)
+		if used+n > budget {
+			flush()
+		}
+		cur.WriteString(line)
+		used += n
+	}
+	flush()
+	return chunks
+}
@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanBodyStripsTrailingFooter(t *testing.T) {
+	body := strings.Join([]string{
+		"Hi Alex,",
+		"Thanks for applying to the Backend Engineer role. We'd like to schedule a phone screen next week.",
+		"Best,\nJamie",
+		"CONFIDENTIALITY NOTICE: This email and any attachments are confidential and may be privileged. " +
+			"If you are not the intended recipient, please delete it.",
+		"You are receiving this because you applied on our careers site. To unsubscribe, click here.",
+		"本邮件及其附件含有保密信息，如果您不是本邮件的预期收件人，请立即删除。",
+	}, "\n\n")
+
+	got := cleanBody(body)
+	if strings.Contains(got, "CONFIDENTIALITY") || strings.Contains(got, "unsubscribe") || strings.Contains(got, "保密") {
+		t.Errorf("footer not removed:\n%s", got)
+	}
+	if !strings.Contains(got, "phone screen") || !strings.HasSuffix(got, "Jamie") {
+		t.Errorf("message body lost:\n%s", got)
+	}
+}
+
+func TestCleanBodyKeepsMidBodyMentions(t *testing.T) {
+	paragraphs := []string{
+		"This email is intended to confirm your onsite interview on March 15 at 10:00.",
+		"If you no longer want to be considered, reply and we will unsubscribe you from this requisition.",
+		"您的面试安排如下，如需退订职位推荐可在个人中心设置。",
+		"Please bring a photo ID.",
+	}
+	body := strings.Join(paragraphs, "\n\n")
+
+	got := cleanBody(body)
+	for _, p := range paragraphs {
+		if !strings.Contains(got, p) {
+			t.Errorf("paragraph removed: %q\ngot:\n%s", p, got)
+		}
+	}
+}
+
+func TestCleanBodyQuotedAndSignature(t *testing.T) {
+	body := "Looking forward to talking.\n\n-- \nJamie Recruiter\nAcme Inc.\n\n" +
+		"On Mon, Mar 4, 2024 at 9:00 AM Alex <alex@example.com> wrote:\n> Hi, any update?"
+	if got := cleanBody(body); got != "Looking forward to talking." {
+		t.Errorf("cleanBody = %q", got)
+	}
+
+	body = "感谢您的回复。\n\n发自我的iPhone\n\n-----原始邮件-----\n发件人: hr@acme.com\n面试时间改到周五"
+	if got := cleanBody(body); got != "感谢您的回复。" {
+		t.Errorf("cleanBody = %q", got)
+	}
+
+	// 整封都是引用时返回原文
+	quoted := "> only quoted text"
+	if got := cleanBody(quoted); got != quoted {
+		t.Errorf("cleanBody = %q, want original", got)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	text := strings.Repeat("第一行内容\n", 50)
+	chunks := splitChunks(text, 30)
+	if len(chunks) < 2 {
+		t.Fatalf("chunks = %d, want several", len(chunks))
+	}
+	for _, c := range chunks {
+		if n := estimateTokens(c); n > 31 {
+			t.Errorf("chunk over budget: %d tokens", n)
+		}
+	}
+	if got := truncateTokens("你好世界你好世界", 4); got != "你好世界..." {
+		t.Errorf("truncateTokens = %q", got)
+	}
+}
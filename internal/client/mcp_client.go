@@ -1,13 +1,13 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/YKarmar/JobTracker/internal/types"
@@ -16,9 +16,9 @@ import (
 // MCP协议相关结构体
 type MCPRequest struct {
 	Jsonrpc string      `json:"jsonrpc"`
-	ID      string      `json:"id"`
+	ID      string      `json:"id,omitempty"`
 	Method  string      `json:"method"`
-	Params  interface{} `json:"params"`
+	Params  interface{} `json:"params,omitempty"`
 }
 
 type MCPResponse struct {
@@ -50,6 +50,10 @@ type MCPEmailConfig struct {
 	Email       string        `json:"email"`
 	MCPEndpoint string        `json:"mcp_endpoint"`
 	APIKey      string        `json:"api_key,omitempty"`
+
+	// 设置 Command 时以子进程方式启动MCP服务器并通过stdio通信，忽略 MCPEndpoint
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
 }
 
 // 邮件查询参数
@@ -59,12 +63,48 @@ type EmailQuery struct {
 	MaxEmails int       `json:"max_emails"`
 	Folders   []string  `json:"folders,omitempty"`
 	Keywords  []string  `json:"keywords,omitempty"`
+
+	// 发件人域名和主题片段，与关键词任一匹配即可（由服务器端搜索）
+	SenderDomains   []string `json:"sender_domains,omitempty"`
+	SubjectPatterns []string `json:"subject_patterns,omitempty"`
+
+	// OnProgress 接收服务器推送的抓取进度（可选）
+	OnProgress func(Progress) `json:"-"`
+}
+
+// 一页抓取结果，邮件按文件夹顺序、同一文件夹内从新到旧排列
+type FetchPage struct {
+	Emails []types.Email `json:"emails"`
+	// 还有更多邮件时非空，传给 FetchEmailsPage 获取下一页
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// 增量同步结果
+type SyncResult struct {
+	Emails []types.Email `json:"emails"`
+	// 下次同步时传回服务器的不透明游标
+	Cursor string `json:"cursor"`
+	// 新邮件超过 MaxEmails 时为 true
+	HasMore bool `json:"has_more"`
+	// 因首次同步或 UIDVALIDITY 变化而全量同步的文件夹
+	Resynced []string `json:"resynced,omitempty"`
+}
+
+// 服务器推送的进度（notifications/progress）
+type Progress struct {
+	Progress float64 `json:"progress"`
+	Total    float64 `json:"total,omitempty"`
+	Message  string  `json:"message,omitempty"`
 }
 
 // MCP邮件客户端
 type MCPEmailClient struct {
 	config     MCPEmailConfig
 	httpClient *http.Client
+
+	mu        sync.Mutex
+	transport transport
+	nextID    int64
 }
 
 // 登录会话信息
@@ -79,9 +119,8 @@ type LoginSession struct {
 func NewMCPEmailClient(config MCPEmailConfig) *MCPEmailClient {
 	return &MCPEmailClient{
 		config: config,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		// 不设置整体超时：长时间抓取通过SSE持续返回进度，由调用方的 context 控制取消
+		httpClient: &http.Client{},
 	}
 }
 
@@ -101,8 +140,31 @@ func ParseEmailProvider(provider string) EmailProvider {
 	}
 }
 
-// 通过MCP协议获取邮件
+// 通过MCP协议获取邮件：从最新的邮件开始逐页获取，直到达到 query.MaxEmails 或没有更多邮件
 func (c *MCPEmailClient) FetchEmails(ctx context.Context, query EmailQuery) ([]types.Email, error) {
+	var emails []types.Email
+	pageToken := ""
+
+	for {
+		pageQuery := query
+		pageQuery.MaxEmails = query.MaxEmails - len(emails)
+
+		page, err := c.FetchEmailsPage(ctx, pageQuery, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, page.Emails...)
+
+		pageToken = page.NextPageToken
+		if pageToken == "" || len(emails) >= query.MaxEmails {
+			return emails, nil
+		}
+	}
+}
+
+// 获取一页邮件，pageToken 为空时从第一页开始。服务器单页有上限，
+// query.MaxEmails 超过上限时需要用返回的 NextPageToken 继续获取
+func (c *MCPEmailClient) FetchEmailsPage(ctx context.Context, query EmailQuery, pageToken string) (*FetchPage, error) {
 	// 构建MCP请求
 	params := map[string]interface{}{
 		"provider":   c.config.Provider,
@@ -112,59 +174,51 @@ func (c *MCPEmailClient) FetchEmails(ctx context.Context, query EmailQuery) ([]t
 		"max_emails": query.MaxEmails,
 		"folders":    query.Folders,
 		"keywords":   query.Keywords,
-	}
 
-	mcpReq := MCPRequest{
-		Jsonrpc: "2.0",
-		ID:      fmt.Sprintf("fetch_%d", time.Now().Unix()),
-		Method:  "email.fetch",
-		Params:  params,
+		"sender_domains":   query.SenderDomains,
+		"subject_patterns": query.SubjectPatterns,
 	}
-
-	// 发送MCP请求
-	reqBody, err := json.Marshal(mcpReq)
-	if err != nil {
-		return nil, fmt.Errorf("marshal MCP request: %w", err)
+	if pageToken != "" {
+		params["page_token"] = pageToken
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.config.MCPEndpoint, bytes.NewBuffer(reqBody))
+	result, err := c.call(ctx, "fetch", "email.fetch", params, query.OnProgress)
 	if err != nil {
-		return nil, fmt.Errorf("create HTTP request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if c.config.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	// 解析邮件数据
+	var page FetchPage
+	if err := json.Unmarshal(result, &page); err != nil {
+		return nil, fmt.Errorf("unmarshal emails: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("MCP server error: %s", string(body))
-	}
+	return &page, nil
+}
 
-	// 解析MCP响应
-	var mcpResp MCPResponse
-	if err := json.NewDecoder(resp.Body).Decode(&mcpResp); err != nil {
-		return nil, fmt.Errorf("decode MCP response: %w", err)
+// 增量同步：只获取 cursor 之后的新邮件。cursor 为空时使用服务器保存的检查点，
+// 首次同步从 query.StartDate 开始；query.EndDate 不起作用
+func (c *MCPEmailClient) SyncEmails(ctx context.Context, query EmailQuery, cursor string) (*SyncResult, error) {
+	params := map[string]interface{}{
+		"provider":   c.config.Provider,
+		"email":      c.config.Email,
+		"start_date": query.StartDate.Format(time.RFC3339),
+		"max_emails": query.MaxEmails,
+		"folders":    query.Folders,
+		"cursor":     cursor,
 	}
 
-	if mcpResp.Error != nil {
-		return nil, fmt.Errorf("MCP error: %s", mcpResp.Error.Message)
+	result, err := c.call(ctx, "sync", "email.sync", params, query.OnProgress)
+	if err != nil {
+		return nil, err
 	}
 
-	// 解析邮件数据
-	var emails []types.Email
-	if err := json.Unmarshal(mcpResp.Result, &emails); err != nil {
-		return nil, fmt.Errorf("unmarshal emails: %w", err)
+	var syncResult SyncResult
+	if err := json.Unmarshal(result, &syncResult); err != nil {
+		return nil, fmt.Errorf("unmarshal sync result: %w", err)
 	}
 
-	return emails, nil
+	return &syncResult, nil
 }
 
 // 触发邮箱登录
@@ -174,44 +228,145 @@ func (c *MCPEmailClient) InitiateEmailLogin(ctx context.Context) (*LoginSession,
 		"email":    c.config.Email,
 	}
 
-	mcpReq := MCPRequest{
-		Jsonrpc: "2.0",
-		ID:      fmt.Sprintf("login_%d", time.Now().Unix()),
-		Method:  "email.login",
-		Params:  params,
-	}
-
-	reqBody, err := json.Marshal(mcpReq)
+	result, err := c.call(ctx, "login", "email.login", params, nil)
 	if err != nil {
-		return nil, fmt.Errorf("marshal MCP request: %w", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.config.MCPEndpoint, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("create HTTP request: %w", err)
+	var session LoginSession
+	if err := json.Unmarshal(result, &session); err != nil {
+		return nil, fmt.Errorf("unmarshal login session: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	return &session, nil
+}
+
+// Close 释放传输资源，stdio模式下会结束MCP服务器子进程
+func (c *MCPEmailClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	resp, err := c.httpClient.Do(req)
+	if c.transport == nil {
+		return nil
+	}
+	err := c.transport.Close()
+	c.transport = nil
+	return err
+}
+
+// call 发送一条MCP请求并返回结果。onProgress 不为空时请求进度通知；
+// ctx 被取消时向服务器发送 notifications/cancelled
+func (c *MCPEmailClient) call(ctx context.Context, idPrefix, method string, params map[string]interface{}, onProgress func(Progress)) (json.RawMessage, error) {
+	t, err := c.getTransport(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var mcpResp MCPResponse
-	if err := json.NewDecoder(resp.Body).Decode(&mcpResp); err != nil {
-		return nil, fmt.Errorf("decode MCP response: %w", err)
+	req := c.newRequest(idPrefix, method, params)
+
+	var onNotify notifyFunc
+	if onProgress != nil {
+		// 进度令牌直接使用请求ID
+		params["_meta"] = map[string]interface{}{"progressToken": req.ID}
+		onNotify = func(method string, raw json.RawMessage) {
+			if method != "notifications/progress" {
+				return
+			}
+			var progress Progress
+			if err := json.Unmarshal(raw, &progress); err == nil {
+				onProgress(progress)
+			}
+		}
+	}
+
+	mcpResp, err := t.roundTrip(ctx, req, onNotify)
+	if err != nil {
+		if ctx.Err() != nil {
+			c.cancelRequest(t, req.ID, ctx.Err().Error())
+		}
+		return nil, err
 	}
 
 	if mcpResp.Error != nil {
 		return nil, fmt.Errorf("MCP error: %s", mcpResp.Error.Message)
 	}
 
-	var session LoginSession
-	if err := json.Unmarshal(mcpResp.Result, &session); err != nil {
-		return nil, fmt.Errorf("unmarshal login session: %w", err)
+	return mcpResp.Result, nil
+}
+
+// cancelRequest 通知服务器停止处理已放弃的请求
+func (c *MCPEmailClient) cancelRequest(t transport, id, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.notify(ctx, MCPRequest{
+		Jsonrpc: "2.0",
+		Method:  "notifications/cancelled",
+		Params: map[string]interface{}{
+			"requestId": id,
+			"reason":    reason,
+		},
+	})
+}
+
+func (c *MCPEmailClient) newRequest(idPrefix, method string, params interface{}) MCPRequest {
+	return MCPRequest{
+		Jsonrpc: "2.0",
+		ID:      fmt.Sprintf("%s_%d", idPrefix, atomic.AddInt64(&c.nextID, 1)),
+		Method:  method,
+		Params:  params,
 	}
+}
 
-	return &session, nil
+// getTransport 按配置创建传输，stdio模式首次使用时启动子进程并完成MCP握手
+func (c *MCPEmailClient) getTransport(ctx context.Context) (transport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.transport != nil {
+		return c.transport, nil
+	}
+
+	if c.config.Command == "" {
+		c.transport = &httpTransport{
+			endpoint:   c.config.MCPEndpoint,
+			apiKey:     c.config.APIKey,
+			httpClient: c.httpClient,
+		}
+		return c.transport, nil
+	}
+
+	t, err := newStdioTransport(c.config.Command, c.config.Args)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.initialize(ctx, t); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	c.transport = t
+	return t, nil
+}
+
+// initialize 执行MCP握手：initialize 请求 + notifications/initialized 通知
+func (c *MCPEmailClient) initialize(ctx context.Context, t transport) error {
+	params := map[string]interface{}{
+		"protocolVersion": "2025-06-18",
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": map[string]interface{}{
+			"name":    "jobtracker",
+			"version": "0.2.0",
+		},
+	}
+
+	resp, err := t.roundTrip(ctx, c.newRequest("init", "initialize", params), nil)
+	if err != nil {
+		return fmt.Errorf("MCP initialize: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("MCP initialize error: %s", resp.Error.Message)
+	}
+
+	return t.notify(ctx, MCPRequest{Jsonrpc: "2.0", Method: "notifications/initialized"})
 }
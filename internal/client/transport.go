@@ -0,0 +1,317 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// transport 负责把一条MCP请求发送给服务器并返回对应的响应，
+// 等待期间收到的服务器通知交给 onNotify 处理（可为 nil）
+type transport interface {
+	roundTrip(ctx context.Context, req MCPRequest, onNotify notifyFunc) (*MCPResponse, error)
+	notify(ctx context.Context, req MCPRequest) error
+	Close() error
+}
+
+type notifyFunc func(method string, params json.RawMessage)
+
+// incomingMessage 服务器发来的消息：带ID的响应，或带method的通知
+type incomingMessage struct {
+	MCPResponse
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// HTTP传输（MCP Streamable HTTP）：每个请求一次POST，
+// 服务器可以直接返回JSON，也可以返回SSE流先推送通知再返回响应
+type httpTransport struct {
+	endpoint   string
+	apiKey     string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func (t *httpTransport) roundTrip(ctx context.Context, mcpReq MCPRequest, onNotify notifyFunc) (*MCPResponse, error) {
+	resp, err := t.post(ctx, mcpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("MCP server error: %s", string(body))
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return t.readEventStream(ctx, resp.Body, mcpReq.ID, onNotify)
+	}
+
+	var mcpResp MCPResponse
+	if err := json.NewDecoder(resp.Body).Decode(&mcpResp); err != nil {
+		return nil, fmt.Errorf("decode MCP response: %w", err)
+	}
+	return &mcpResp, nil
+}
+
+// readEventStream 逐个读取SSE事件，分发通知，直到收到本请求的响应
+func (t *httpTransport) readEventStream(ctx context.Context, body io.Reader, id string, onNotify notifyFunc) (*MCPResponse, error) {
+	reader := bufio.NewReader(body)
+	var data bytes.Buffer
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && data.Len() > 0:
+			// 空行表示一个事件结束
+			var msg incomingMessage
+			if jsonErr := json.Unmarshal(data.Bytes(), &msg); jsonErr == nil {
+				if msg.Method != "" {
+					if onNotify != nil {
+						onNotify(msg.Method, msg.Params)
+					}
+				} else if msg.ID == id {
+					return &msg.MCPResponse, nil
+				}
+			}
+			data.Reset()
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("MCP event stream closed before response: %w", err)
+		}
+	}
+}
+
+func (t *httpTransport) notify(ctx context.Context, mcpReq MCPRequest) error {
+	resp, err := t.post(ctx, mcpReq)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *httpTransport) post(ctx context.Context, mcpReq MCPRequest) (*http.Response, error) {
+	reqBody, err := json.Marshal(mcpReq)
+	if err != nil {
+		return nil, fmt.Errorf("marshal MCP request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.endpoint, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	// 服务器在 initialize 响应中分配会话ID，后续请求需要带上
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) Close() error { return nil }
+
+// stdio传输：启动MCP服务器子进程，通过标准输入/输出交换换行分隔的JSON-RPC消息
+type stdioTransport struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[string]*pendingCall
+	readErr error
+	done    chan struct{}
+}
+
+// pendingCall 等待响应的请求，进度令牌与请求ID相同
+type pendingCall struct {
+	ch       chan *MCPResponse
+	onNotify notifyFunc
+}
+
+func newStdioTransport(command string, args []string) (*stdioTransport, error) {
+	cmd := exec.Command(command, args...)
+	// 服务器日志写在标准错误，直接透传给用户
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("create stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start MCP server %s: %w", command, err)
+	}
+
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[string]*pendingCall),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+
+	return t, nil
+}
+
+// readLoop 读取服务器输出，按ID把响应分发给等待中的请求
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	var err error
+
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var msg incomingMessage
+			if jsonErr := json.Unmarshal(line, &msg); jsonErr == nil {
+				t.handleMessage(&msg)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	t.mu.Lock()
+	if err == io.EOF {
+		err = fmt.Errorf("MCP server exited")
+	}
+	t.readErr = err
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// handleMessage 响应交给对应的等待者，进度通知按 progressToken 分发
+func (t *stdioTransport) handleMessage(msg *incomingMessage) {
+	if msg.Method != "" {
+		var progress struct {
+			ProgressToken string `json:"progressToken"`
+		}
+		json.Unmarshal(msg.Params, &progress)
+
+		t.mu.Lock()
+		call := t.pending[progress.ProgressToken]
+		t.mu.Unlock()
+
+		if call != nil && call.onNotify != nil {
+			call.onNotify(msg.Method, msg.Params)
+		}
+		return
+	}
+
+	if msg.ID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	call := t.pending[msg.ID]
+	delete(t.pending, msg.ID)
+	t.mu.Unlock()
+
+	if call != nil {
+		call.ch <- &msg.MCPResponse
+	}
+}
+
+func (t *stdioTransport) roundTrip(ctx context.Context, req MCPRequest, onNotify notifyFunc) (*MCPResponse, error) {
+	ch := make(chan *MCPResponse, 1)
+
+	t.mu.Lock()
+	if t.readErr != nil {
+		t.mu.Unlock()
+		return nil, t.readErr
+	}
+	t.pending[req.ID] = &pendingCall{ch: ch, onNotify: onNotify}
+	t.mu.Unlock()
+
+	if err := t.write(req); err != nil {
+		t.forget(req.ID)
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		t.forget(req.ID)
+		return nil, t.readErr
+	case <-ctx.Done():
+		t.forget(req.ID)
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, req MCPRequest) error {
+	return t.write(req)
+}
+
+func (t *stdioTransport) write(req MCPRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal MCP request: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write to MCP server: %w", err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) forget(id string) {
+	t.mu.Lock()
+	delete(t.pending, id)
+	t.mu.Unlock()
+}
+
+// Close 关闭标准输入让服务器退出，并等待子进程结束
+func (t *stdioTransport) Close() error {
+	t.stdin.Close()
+	<-t.done
+	return t.cmd.Wait()
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -21,24 +22,64 @@ type Config struct {
 		Folders           []string `yaml:"folders"`
 	} `yaml:"imap"`
 	MCP struct {
-		Endpoint string `yaml:"endpoint"`
-		APIKey   string `yaml:"api_key"`
+		Endpoint string   `yaml:"endpoint"`
+		APIKey   string   `yaml:"api_key"`
+		Command  string   `yaml:"command"` // 设置后以子进程方式启动MCP服务器（stdio传输）
+		Args     []string `yaml:"args"`
 	} `yaml:"mcp"`
 	Fetch struct {
 		Start     string `yaml:"start"` // YYYY-MM-DD or RFC3339
 		End       string `yaml:"end"`   // YYYY-MM-DD or RFC3339
 		MaxEmails int    `yaml:"max_emails"`
+		// 服务器端筛选条件，任一匹配即可；keywords 显式设为 [] 时不筛选
+		Keywords        []string `yaml:"keywords"`
+		SenderDomains   []string `yaml:"sender_domains"`
+		SubjectPatterns []string `yaml:"subject_patterns"`
 	} `yaml:"fetch"`
 	LLM struct {
-		APIBase     string  `yaml:"api_base"`
+		Provider    string  `yaml:"provider"` // openai（默认，含DeepSeek等兼容接口）、anthropic 或 ollama
+		APIBase     string  `yaml:"api_base"` // 留空使用所选后端的默认地址
 		APIKey      string  `yaml:"api_key"`
 		Model       string  `yaml:"model"`
 		Temperature float64 `yaml:"temperature"`
 		MaxTokens   int     `yaml:"max_tokens"`
+		BodyTokens  int     `yaml:"body_tokens"` // 提示词中邮件正文的token预算，默认等于 max_tokens
+		// 并发与限速，0 表示使用默认并发（4）或不限速
+		Concurrency       int `yaml:"concurrency"`
+		RequestsPerMinute int `yaml:"requests_per_minute"`
+		TokensPerMinute   int `yaml:"tokens_per_minute"`
+		// 结构化输出模式（json_schema/json_object/text）和求职邮件的置信度阈值
+		ResponseFormat string  `yaml:"response_format"`
+		MinConfidence  float64 `yaml:"min_confidence"`
+		// 回复缓存，同一模型、提示词和邮件内容不重复调用LLM
+		Cache struct {
+			Disabled bool   `yaml:"disabled"`
+			Path     string `yaml:"path"`     // 默认 <data_dir>/llm_cache.db
+			TTLDays  int    `yaml:"ttl_days"` // 缓存有效天数，0 表示不过期
+		} `yaml:"cache"`
 	} `yaml:"llm"`
 	Export struct {
-		File string `yaml:"file"`
+		File     string `yaml:"file"`     // 导出文件，"-" 表示标准输出
+		Format   string `yaml:"format"`   // csv（默认）、json、ndjson、markdown、html 或 xlsx
+		Calendar string `yaml:"calendar"` // 笔试/面试日历（.ics）路径，留空不导出
+		// 合并到已有的CSV（保留手动添加的列和修改），而不是覆盖，仅用于csv格式
+		Merge bool `yaml:"merge"`
 	} `yaml:"export"`
+	Followups struct {
+		// 各阶段（APPLIED/OA/INTERVIEW）多少天没有新邮件时提醒跟进、视为无回音，0 表示不提醒
+		StaleDays   map[string]int `yaml:"stale_days"`
+		GhostedDays map[string]int `yaml:"ghosted_days"`
+	} `yaml:"followups"`
+	Store struct {
+		Path string `yaml:"path"` // 申请库文件，默认 <data_dir>/applications.db
+	} `yaml:"store"`
+	MailCache struct {
+		Dir string `yaml:"dir"` // 原始邮件缓存目录，默认 <data_dir>/mail
+		// 最后一次获取后保留的天数和最多保留的邮件数，0 表示不限制；每次获取邮件后清理，本次获取的邮件不清理
+		RetentionDays int `yaml:"retention_days"`
+		MaxMessages   int `yaml:"max_messages"`
+	} `yaml:"mail_cache"`
+	DataDir string `yaml:"data_dir"` // 本地数据目录（同步游标等），默认 ~/.jobtracker
 }
 
 // Load 加载配置文件并替换环境变量
@@ -88,14 +129,60 @@ func Load(path string) (*Config, error) {
 		cfg.Fetch.MaxEmails = 100
 	}
 
+	// LLM后端
+	cfg.LLM.Provider = strings.ToLower(strings.TrimSpace(cfg.LLM.Provider))
+	switch cfg.LLM.Provider {
+	case "":
+		cfg.LLM.Provider = "openai"
+	case "openai", "anthropic", "ollama":
+	default:
+		return nil, fmt.Errorf("unknown llm.provider %q (supported: openai, anthropic, ollama)", cfg.LLM.Provider)
+	}
+
+	// 未配置API密钥（或引用的环境变量不存在）时，使用后端对应的标准环境变量
+	if cfg.LLM.APIKey == "" || strings.HasPrefix(cfg.LLM.APIKey, "${") {
+		cfg.LLM.APIKey = os.Getenv(providerKeyEnv[cfg.LLM.Provider])
+	}
+
+	// 默认筛选关键词（中英文）
+	if cfg.Fetch.Keywords == nil {
+		cfg.Fetch.Keywords = defaultKeywords
+	}
+
 	// 默认导出文件
 	if cfg.Export.File == "" {
 		cfg.Export.File = "emails.csv"
 	}
+	if cfg.Export.Format == "" {
+		cfg.Export.Format = "csv"
+	}
+
+	// 默认数据目录
+	if cfg.DataDir == "" {
+		cfg.DataDir = defaultDataDir()
+	}
+	if cfg.Store.Path == "" {
+		cfg.Store.Path = filepath.Join(cfg.DataDir, "applications.db")
+	}
+	if cfg.LLM.Cache.Path == "" {
+		cfg.LLM.Cache.Path = filepath.Join(cfg.DataDir, "llm_cache.db")
+	}
+	if cfg.MailCache.Dir == "" {
+		cfg.MailCache.Dir = filepath.Join(cfg.DataDir, "mail")
+	}
 
 	return &cfg, nil
 }
 
+// providerKeyEnv 各LLM后端的标准API密钥环境变量（Ollama不需要密钥）
+var providerKeyEnv = map[string]string{
+	"openai":    "OPENAI_API_KEY",
+	"anthropic": "ANTHROPIC_API_KEY",
+}
+
+// defaultKeywords 未配置 fetch.keywords 时使用的求职相关关键词
+var defaultKeywords = []string{"job", "interview", "offer", "application", "招聘", "面试", "职位", "工作"}
+
 // expandEnvVars 替换 ${VAR_NAME} 格式的环境变量
 func expandEnvVars(content string) string {
 	re := regexp.MustCompile(`\$\{([^}]+)\}`)
@@ -158,6 +245,40 @@ func getDefaultFolders(provider string) []string {
 	}
 }
 
+// Locate 确定配置文件路径：显式指定的路径优先，其次是 $JOBTRACKER_CONFIG、
+// 当前目录下的 configs/config.yaml 和数据目录下的 config.yaml，都不存在时返回错误
+func Locate(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if env := os.Getenv("JOBTRACKER_CONFIG"); env != "" {
+		return env, nil
+	}
+
+	candidates := []string{
+		filepath.Join("configs", "config.yaml"),
+		filepath.Join(defaultDataDir(), "config.yaml"),
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("config file not found (tried %s); use --config or JOBTRACKER_CONFIG", strings.Join(candidates, ", "))
+}
+
+// defaultDataDir 默认数据目录，与MCP服务器保持一致
+func defaultDataDir() string {
+	if dir := os.Getenv("JOBTRACKER_DATA_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".jobtracker"
+	}
+	return filepath.Join(home, ".jobtracker")
+}
+
 func hasSuffixInsensitive(s, suf string) bool {
 	sLower := strings.ToLower(s)
 	sufLower := strings.ToLower(suf)
@@ -3,10 +3,12 @@ package exporter
 import (
 	"encoding/csv"
 	"fmt"
-	"os"
+	"io"
+	"path/filepath"
 	"strconv"
-	"time"
+	"strings"
 
+	"github.com/YKarmar/JobTracker/internal/store"
 	"github.com/YKarmar/JobTracker/internal/types"
 )
 
@@ -22,182 +24,178 @@ func NewCSVExporter(filename string) *CSVExporter {
 	}
 }
 
-// 导出求职信息到CSV文件
-func (ce *CSVExporter) ExportJobApplications(applications []types.JobApplication) error {
-	file, err := os.Create(ce.filename)
-	if err != nil {
-		return fmt.Errorf("create CSV file: %w", err)
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// 写入CSV头部
-	headers := []string{
-		"公司名称",
-		"职位名称",
-		"状态",
-		"工作地点",
-		"状态描述",
-		"邮件发件人",
-		"邮件主题",
-		"邮件日期",
-		"邮件文件夹",
-		"信息提取时间",
+func init() {
+	Register("csv", func() Exporter { return &CSVExporter{} })
+}
+
+func (ce *CSVExporter) Extension() string { return ".csv" }
+
+// 申请CSV的列，均由程序生成（合并模式下会被更新）。
+// Message-ID 为申请首封邮件的 Message-ID，合并时用来匹配已有的行
+var applicationHeaders = []string{
+	"公司名称",
+	"职位名称",
+	"职位编号",
+	"当前状态",
+	"工作地点",
+	"状态描述",
+	"邮件数量",
+	"首封邮件日期",
+	"最近邮件日期",
+	"最近邮件主题",
+	"面试轮次",
+	"状态时间线",
+	"异常状态变化",
+	"Message-ID",
+}
+
+// applicationRecord 申请的一行，与 applicationHeaders 对应
+func applicationRecord(app store.Application) []string {
+	var first, latest store.LinkedEmail
+	if len(app.Emails) > 0 {
+		first, latest = app.Emails[0], app.Emails[len(app.Emails)-1]
+	}
+
+	return []string{
+		app.Company,
+		app.Position,
+		app.RequisitionID,
+		string(app.Status),
+		app.Location,
+		latest.Description,
+		strconv.Itoa(len(app.Emails)),
+		first.Date.Format("2006-01-02 15:04:05"),
+		latest.Date.Format("2006-01-02 15:04:05"),
+		latest.Subject,
+		strconv.Itoa(app.Rounds()),
+		app.TimelineString(),
+		strconv.Itoa(len(app.Flagged())),
+		first.MessageID,
 	}
+}
+
+// 把申请写成CSV，每个申请一行
+func (ce *CSVExporter) Write(w io.Writer, applications []store.Application) error {
+	writer := csv.NewWriter(w)
 
-	if err := writer.Write(headers); err != nil {
+	if err := writer.Write(applicationHeaders); err != nil {
 		return fmt.Errorf("write CSV headers: %w", err)
 	}
 
-	// 写入数据行
 	for _, app := range applications {
-		record := []string{
-			app.Company,
-			app.Position,
-			string(app.Status),
-			app.Location,
-			app.Description,
-			app.Email.From,
-			app.Email.Subject,
-			app.Email.Date.Format("2006-01-02 15:04:05"),
-			app.Email.Folder,
-			app.ExtractedAt.Format("2006-01-02 15:04:05"),
-		}
-
-		if err := writer.Write(record); err != nil {
+		if err := writer.Write(applicationRecord(app)); err != nil {
 			return fmt.Errorf("write CSV record: %w", err)
 		}
 	}
 
-	return nil
+	writer.Flush()
+	return writer.Error()
 }
 
-// 导出统计信息到CSV文件
-func (ce *CSVExporter) ExportStatistics(applications []types.JobApplication) error {
-	statsFile := "job_statistics_" + time.Now().Format("20060102_150405") + ".csv"
-
-	file, err := os.Create(statsFile)
-	if err != nil {
-		return fmt.Errorf("create statistics file: %w", err)
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// 导出所有申请的状态时间线，每次状态变化一行，文件名为 <导出文件名>_timeline.csv
+func (ce *CSVExporter) ExportTimeline(applications []store.Application) (string, error) {
+	timelineFile := strings.TrimSuffix(ce.filename, filepath.Ext(ce.filename)) + "_timeline.csv"
 
-	// 统计各种状态的数量
-	statusCount := make(map[types.Status]int)
-	companyCount := make(map[string]int)
+	err := writeFileAtomic(timelineFile, func(w io.Writer) error {
+		writer := csv.NewWriter(w)
 
-	for _, app := range applications {
-		statusCount[app.Status]++
-		companyCount[app.Company]++
-	}
+		headers := []string{"公司名称", "职位名称", "日期", "原状态", "新状态", "面试轮次", "邮件主题", "是否异常", "说明"}
+		if err := writer.Write(headers); err != nil {
+			return fmt.Errorf("write CSV headers: %w", err)
+		}
 
-	// 写入状态统计
-	writer.Write([]string{"状态统计"})
-	writer.Write([]string{"状态", "数量"})
+		for _, app := range applications {
+			for _, t := range app.Timeline {
+				round := ""
+				if t.Round > 0 {
+					round = strconv.Itoa(t.Round)
+				}
+				invalid := ""
+				if t.Invalid {
+					invalid = "是"
+				}
+
+				record := []string{
+					app.Company,
+					app.Position,
+					t.At.Format("2006-01-02 15:04:05"),
+					string(t.From),
+					string(t.To),
+					round,
+					t.Subject,
+					invalid,
+					t.Note,
+				}
+				if err := writer.Write(record); err != nil {
+					return fmt.Errorf("write CSV record: %w", err)
+				}
+			}
+		}
 
-	for status, count := range statusCount {
-		writer.Write([]string{string(status), strconv.Itoa(count)})
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return "", err
 	}
+	return timelineFile, nil
+}
 
-	writer.Write([]string{}) // 空行
-
-	// 写入公司统计（前10名）
-	writer.Write([]string{"公司投递统计（前10名）"})
-	writer.Write([]string{"公司名称", "投递次数"})
-
-	// 简单排序找出前10名
-	type companyStats struct {
-		name  string
-		count int
-	}
+// 导出统计信息，文件名为 <导出文件名>_statistics.csv，与导出文件在同一目录
+func (ce *CSVExporter) ExportStatistics(applications []types.JobApplication) (string, error) {
+	statsFile := strings.TrimSuffix(ce.filename, filepath.Ext(ce.filename)) + "_statistics.csv"
+	stats := computeStatistics(applications)
 
-	var companies []companyStats
-	for company, count := range companyCount {
-		companies = append(companies, companyStats{company, count})
-	}
+	err := writeFileAtomic(statsFile, func(w io.Writer) error {
+		writer := csv.NewWriter(w)
 
-	// 简单冒泡排序
-	for i := 0; i < len(companies)-1; i++ {
-		for j := 0; j < len(companies)-i-1; j++ {
-			if companies[j].count < companies[j+1].count {
-				companies[j], companies[j+1] = companies[j+1], companies[j]
-			}
+		// 状态统计
+		records := [][]string{{"状态统计"}, {"状态", "数量"}}
+		for _, status := range stats.statuses() {
+			records = append(records, []string{string(status), strconv.Itoa(stats.statusCount[status])})
 		}
-	}
+		records = append(records, []string{}) // 空行
 
-	// 取前10名
-	limit := 10
-	if len(companies) < limit {
-		limit = len(companies)
-	}
+		// 公司统计（前10名）
+		records = append(records, []string{"公司投递统计（前10名）"}, []string{"公司名称", "投递次数"})
+		for _, c := range stats.topCompanies(10) {
+			records = append(records, []string{c.name, strconv.Itoa(c.count)})
+		}
 
-	for i := 0; i < limit; i++ {
-		writer.Write([]string{companies[i].name, strconv.Itoa(companies[i].count)})
+		if err := writer.WriteAll(records); err != nil {
+			return fmt.Errorf("write CSV record: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
-
-	fmt.Printf("统计信息已导出到: %s\n", statsFile)
-	return nil
+	return statsFile, nil
 }
 
 // 打印简要统计信息
-func PrintJobStatistics(applications []types.JobApplication) {
+func PrintJobStatistics(w io.Writer, applications []types.JobApplication) {
 	if len(applications) == 0 {
-		fmt.Println("没有找到求职相关的邮件")
+		fmt.Fprintln(w, "没有找到求职相关的邮件")
 		return
 	}
 
-	fmt.Printf("\n=== 求职邮件统计 ===\n")
-	fmt.Printf("总共找到 %d 封求职相关邮件\n\n", len(applications))
+	stats := computeStatistics(applications)
 
-	// 统计各状态数量
-	statusCount := make(map[types.Status]int)
-	for _, app := range applications {
-		statusCount[app.Status]++
-	}
-
-	fmt.Println("状态分布:")
-	statusNames := map[types.Status]string{
-		types.StatusApplied:   "已申请",
-		types.StatusOA:        "在线测试/笔试",
-		types.StatusInterview: "面试",
-		types.StatusOffer:     "收到Offer",
-		types.StatusRejected:  "被拒绝",
-		types.StatusWithdrawn: "撤回申请",
-		types.StatusOther:     "其他状态",
-	}
-
-	for status, count := range statusCount {
-		name := statusNames[status]
-		if name == "" {
-			name = string(status)
-		}
-		fmt.Printf("  %s: %d 封\n", name, count)
-	}
+	fmt.Fprintf(w, "\n=== 求职邮件统计 ===\n")
+	fmt.Fprintf(w, "总共找到 %d 封求职相关邮件\n\n", stats.total)
 
-	// 统计公司分布
-	companyCount := make(map[string]int)
-	for _, app := range applications {
-		if app.Company != "" {
-			companyCount[app.Company]++
-		}
+	fmt.Fprintln(w, "状态分布:")
+	for _, status := range stats.statuses() {
+		fmt.Fprintf(w, "  %s: %d 封\n", statusName(status), stats.statusCount[status])
 	}
 
-	fmt.Printf("\n涉及公司数量: %d 家\n", len(companyCount))
+	fmt.Fprintf(w, "\n涉及公司数量: %d 家\n", len(stats.companies))
 
-	if len(companyCount) > 0 {
-		fmt.Println("\n投递最多的公司:")
-		count := 0
-		for company, num := range companyCount {
-			if count >= 5 { // 只显示前5名
-				break
-			}
-			fmt.Printf("  %s: %d 次\n", company, num)
-			count++
+	if len(stats.companies) > 0 {
+		fmt.Fprintln(w, "\n投递最多的公司:")
+		for _, c := range stats.topCompanies(5) { // 只显示前5名
+			fmt.Fprintf(w, "  %s: %d 次\n", c.name, c.count)
 		}
 	}
 }
@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+func TestExportStatistics(t *testing.T) {
+	dir := t.TempDir()
+	ce := NewCSVExporter(filepath.Join(dir, "jobs.csv"))
+	records := []types.JobApplication{
+		{Company: "Acme", Status: types.StatusApplied},
+		{Company: "Acme", Status: types.StatusInterview},
+		{Company: "Globex", Status: types.StatusApplied},
+	}
+
+	path, err := ce.ExportStatistics(records)
+	if err != nil {
+		t.Fatalf("ExportStatistics: %v", err)
+	}
+	if want := filepath.Join(dir, "jobs_statistics.csv"); path != want {
+		t.Errorf("statistics file = %s, want %s", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"APPLIED,2", "INTERVIEW,1", "Acme,2", "Globex,1"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("statistics missing %q:\n%s", want, data)
+		}
+	}
+
+	// 只留下导出的文件，没有残留的临时文件
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("files in output dir = %v, want only the statistics file", entries)
+	}
+}
@@ -0,0 +1,219 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+)
+
+// UTF-8 BOM，Excel 保存的CSV通常带有
+const utf8BOM = "\ufeff"
+
+// MergeResult 合并导出的结果
+type MergeResult struct {
+	Updated   int // 内容有变化的已有行
+	Added     int // 新增的行
+	Preserved int // 保留的手动修改（单元格数）
+}
+
+// MergeApplications 把申请合并到已有的CSV文件，而不是覆盖：
+//   - 按 Message-ID 列匹配已有的行（旧文件没有该列时按公司+职位匹配），更新程序生成的列
+//   - 用户自己加的列和匹配不到申请的行原样保留
+//   - 程序生成的列被手动改过（与上次导出的值不同）时保留手动修改
+//   - 新的申请追加到末尾
+//
+// 上次导出的值记录在同目录的隐藏文件 .<文件名>.state.json 中。文件不存在时等同于普通导出
+func (ce *CSVExporter) MergeApplications(applications []store.Application) (MergeResult, error) {
+	var result MergeResult
+
+	records, hasBOM, err := readCSVFile(ce.filename)
+	if err != nil {
+		return result, err
+	}
+	if len(records) == 0 {
+		records = [][]string{append([]string(nil), applicationHeaders...)}
+	}
+
+	// 表头：保留原有列顺序，缺少的程序列（如新增的 Message-ID）追加到末尾
+	header := records[0]
+	columns := make(map[string]int)
+	for i, name := range header {
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+	for _, name := range applicationHeaders {
+		if _, ok := columns[name]; !ok {
+			columns[name] = len(header)
+			header = append(header, name)
+		}
+	}
+	messageIDCol := columns["Message-ID"]
+
+	// 任一关联邮件的 Message-ID 都能匹配到申请，首封邮件变化后旧行仍能找到
+	byMessageID := make(map[string]int)
+	byKey := make(map[string]int)
+	for i, app := range applications {
+		byKey[app.Key] = i
+		for _, e := range app.Emails {
+			byMessageID[e.MessageID] = i
+		}
+	}
+
+	lastExported, err := loadMergeState(ce.filename)
+	if err != nil {
+		return result, err
+	}
+	state := make(map[string]map[string]string)
+	matched := make(map[int]bool)
+
+	rows := records[1:]
+	for r, row := range rows {
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		rows[r] = row
+
+		i, ok := matchRow(row, columns, byMessageID, byKey)
+		if !ok {
+			continue // 手动添加或已不在申请库中的行
+		}
+		matched[i] = true
+
+		previous := lastExported[row[messageIDCol]]
+		values := applicationRecord(applications[i])
+		changed := false
+		for c, name := range applicationHeaders {
+			col := columns[name]
+			if last, ok := previous[name]; ok && row[col] != last {
+				result.Preserved++
+				continue
+			}
+			if row[col] != values[c] {
+				row[col] = values[c]
+				changed = true
+			}
+		}
+		if changed {
+			result.Updated++
+		}
+		state[row[messageIDCol]] = exportedValues(values)
+	}
+
+	for i, app := range applications {
+		if matched[i] {
+			continue
+		}
+		row := make([]string, len(header))
+		values := applicationRecord(app)
+		for c, name := range applicationHeaders {
+			row[columns[name]] = values[c]
+		}
+		rows = append(rows, row)
+		state[row[messageIDCol]] = exportedValues(values)
+		result.Added++
+	}
+
+	err = writeFileAtomic(ce.filename, func(w io.Writer) error {
+		if hasBOM {
+			if _, err := io.WriteString(w, utf8BOM); err != nil {
+				return fmt.Errorf("write CSV: %w", err)
+			}
+		}
+		writer := csv.NewWriter(w)
+		if err := writer.WriteAll(append([][]string{header}, rows...)); err != nil {
+			return fmt.Errorf("write CSV: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, saveMergeState(ce.filename, state)
+}
+
+// matchRow 找到行对应的申请：优先按 Message-ID，没有该值时按公司、职位和职位编号
+func matchRow(row []string, columns map[string]int, byMessageID, byKey map[string]int) (int, bool) {
+	if id := strings.Trim(strings.TrimSpace(row[columns["Message-ID"]]), "<>"); id != "" {
+		i, ok := byMessageID[id]
+		return i, ok
+	}
+
+	company := row[columns["公司名称"]]
+	if strings.TrimSpace(company) == "" {
+		return 0, false
+	}
+	i, ok := byKey[store.ApplicationKey(company, row[columns["职位名称"]], row[columns["职位编号"]])]
+	return i, ok
+}
+
+// readCSVFile 读取已有CSV，文件不存在时返回空。返回的第二个值表示文件是否以BOM开头
+func readCSVFile(path string) ([][]string, bool, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read CSV file: %w", err)
+	}
+
+	content := string(b)
+	hasBOM := strings.HasPrefix(content, utf8BOM)
+	content = strings.TrimPrefix(content, utf8BOM)
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1 // 手动编辑过的文件各行列数可能不同
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, false, fmt.Errorf("parse existing CSV %s: %w", path, err)
+	}
+	return records, hasBOM, nil
+}
+
+func exportedValues(values []string) map[string]string {
+	m := make(map[string]string, len(values))
+	for i, name := range applicationHeaders {
+		m[name] = values[i]
+	}
+	return m
+}
+
+// mergeStatePath 记录上次导出值的文件
+func mergeStatePath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".state.json")
+}
+
+// loadMergeState 读取上次导出的值（Message-ID -> 列名 -> 值），文件不存在时返回空
+func loadMergeState(path string) (map[string]map[string]string, error) {
+	state := make(map[string]map[string]string)
+
+	b, err := os.ReadFile(mergeStatePath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read merge state: %w", err)
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("parse merge state: %w", err)
+	}
+	return state, nil
+}
+
+func saveMergeState(path string, state map[string]map[string]string) error {
+	return writeFileAtomic(mergeStatePath(path), func(w io.Writer) error {
+		if err := json.NewEncoder(w).Encode(state); err != nil {
+			return fmt.Errorf("write merge state: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+)
+
+// Exporter 把申请列表写成某种格式
+type Exporter interface {
+	// Write 把申请写入 w
+	Write(w io.Writer, applications []store.Application) error
+	// Extension 默认文件扩展名（含点）
+	Extension() string
+}
+
+// 已注册的导出格式
+var registry = map[string]func() Exporter{}
+
+// Register 注册导出格式，名称不区分大小写
+func Register(format string, factory func() Exporter) {
+	registry[strings.ToLower(format)] = factory
+}
+
+// New 按格式名创建导出器
+func New(format string) (Exporter, error) {
+	factory, ok := registry[strings.ToLower(strings.TrimSpace(format))]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q (supported: %s)", format, strings.Join(Formats(), ", "))
+	}
+	return factory(), nil
+}
+
+// Formats 已注册的格式名，按字母排序
+func Formats() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Export 导出到文件，path 为 "-" 时写到标准输出
+func Export(e Exporter, path string, applications []store.Application) error {
+	if path == "-" {
+		return e.Write(os.Stdout, applications)
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return e.Write(w, applications)
+	})
+}
+
+// writeFileAtomic 先写到同目录的临时文件再重命名，写入中途失败或中断不会破坏原文件
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后为空操作
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", path, err)
+	}
+	// CreateTemp 创建的文件权限为0600，与 os.Create 保持一致
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("chmod %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename to %s: %w", path, err)
+	}
+	return nil
+}
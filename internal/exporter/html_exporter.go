@@ -0,0 +1,541 @@
+package exporter
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+func init() {
+	Register("html", func() Exporter { return HTMLExporter{} })
+}
+
+// HTMLExporter 导出为单个离线HTML报告：转化漏斗、每周邮件数、各公司时间线和可排序筛选的申请表。
+// 图表使用内联SVG/CSS，不依赖任何外部资源
+type HTMLExporter struct{}
+
+func (HTMLExporter) Extension() string { return ".html" }
+
+// 图表中各状态的颜色
+var statusColors = map[types.Status]string{
+	types.StatusApplied:   "#4e79a7",
+	types.StatusOA:        "#f28e2b",
+	types.StatusInterview: "#af7aa1",
+	types.StatusOffer:     "#59a14f",
+	types.StatusRejected:  "#e15759",
+	types.StatusWithdrawn: "#9c9c9c",
+	types.StatusOther:     "#bab0ac",
+}
+
+func statusColor(status types.Status) string {
+	if c := statusColors[status]; c != "" {
+		return c
+	}
+	return statusColors[types.StatusOther]
+}
+
+// 每周邮件图最多显示的周数
+const maxReportWeeks = 26
+
+// 报告模板使用的数据
+type htmlReport struct {
+	GeneratedAt  string
+	Applications int
+	Emails       int
+	Companies    int
+	Funnel       []funnelStep
+	Weeks        []weekBar
+	ChartWidth   int
+	ChartHeight  int
+	Statuses     []statusBar
+	TopCompanies []barItem
+	Groups       []companyGroup
+	Rows         []appRow
+}
+
+type funnelStep struct {
+	Label   string
+	Count   int
+	Width   float64 // 相对申请总数的百分比
+	Percent string  // 占申请总数
+	Rate    string  // 相对上一步的转化率
+	Color   string
+}
+
+type weekBar struct {
+	Label  string
+	Count  int
+	X, Y   float64
+	W, H   float64
+	LabelX float64
+	Show   bool // 周数较多时隔几周标一次日期
+}
+
+type statusBar struct {
+	Name  string
+	Count int
+	Width float64
+	Color string
+}
+
+type barItem struct {
+	Name  string
+	Count int
+	Width float64
+}
+
+type companyGroup struct {
+	Company string
+	Apps    []appRow
+}
+
+type appRow struct {
+	Company     string
+	Position    string
+	Status      types.Status
+	StatusName  string
+	Color       string
+	EmailCount  int
+	First       string
+	Latest      string
+	LatestValue int64 // 排序用
+	Rounds      int
+	Subject     string
+	Emails      []emailLink
+	Steps       []timelineStep
+	Flagged     int
+}
+
+type emailLink struct {
+	Date    string
+	Subject string
+	Status  types.Status
+	Link    template.URL // mid: 链接（RFC 2392），没有Message-ID时为空
+}
+
+type timelineStep struct {
+	Date    string
+	Status  types.Status
+	Round   int
+	Invalid bool
+	Note    string
+	Color   string
+}
+
+func (HTMLExporter) Write(w io.Writer, applications []store.Application) error {
+	report := buildHTMLReport(applications, time.Now())
+	if err := htmlTemplate.Execute(w, report); err != nil {
+		return fmt.Errorf("write HTML: %w", err)
+	}
+	return nil
+}
+
+func buildHTMLReport(applications []store.Application, now time.Time) htmlReport {
+	records := EmailRecords(applications)
+	stats := computeStatistics(records)
+
+	report := htmlReport{
+		GeneratedAt:  now.Format("2006-01-02 15:04"),
+		Applications: len(applications),
+		Emails:       stats.total,
+		Companies:    len(stats.companies),
+		Funnel:       buildFunnel(applications),
+		ChartWidth:   720,
+		ChartHeight:  160,
+	}
+	report.Weeks = buildWeeks(records, report.ChartWidth, report.ChartHeight)
+
+	for _, status := range stats.statuses() {
+		report.Statuses = append(report.Statuses, statusBar{
+			Name:  statusName(status),
+			Count: stats.statusCount[status],
+			Width: percent(stats.statusCount[status], stats.total),
+			Color: statusColor(status),
+		})
+	}
+
+	top := stats.topCompanies(10)
+	for _, c := range top {
+		report.TopCompanies = append(report.TopCompanies, barItem{
+			Name:  c.name,
+			Count: c.count,
+			Width: percent(c.count, top[0].count),
+		})
+	}
+
+	groupIndex := make(map[string]int)
+	for _, app := range applications {
+		row := newAppRow(app)
+		report.Rows = append(report.Rows, row)
+
+		i, ok := groupIndex[app.Company]
+		if !ok {
+			i = len(report.Groups)
+			groupIndex[app.Company] = i
+			report.Groups = append(report.Groups, companyGroup{Company: app.Company})
+		}
+		report.Groups[i].Apps = append(report.Groups[i].Apps, row)
+	}
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Company < report.Groups[j].Company
+	})
+
+	return report
+}
+
+// buildFunnel 各阶段的申请数。推进到更后面阶段的申请也计入前面的阶段（如直接收到面试邀请也算通过了笔试），
+// 被拒或撤回不算推进；所有申请都计入 APPLIED
+func buildFunnel(applications []store.Application) []funnelStep {
+	stages := []types.Status{types.StatusApplied, types.StatusOA, types.StatusInterview, types.StatusOffer}
+	counts := make([]int, len(stages))
+
+	for _, app := range applications {
+		progress := types.StatusApplied.Stage()
+		for _, t := range app.Timeline {
+			if t.Invalid || t.To == types.StatusRejected || t.To == types.StatusWithdrawn {
+				continue
+			}
+			if t.To.Stage() > progress {
+				progress = t.To.Stage()
+			}
+		}
+		for i, stage := range stages {
+			if progress >= stage.Stage() {
+				counts[i]++
+			}
+		}
+	}
+
+	var steps []funnelStep
+	for i, stage := range stages {
+		step := funnelStep{
+			Label:   statusName(stage),
+			Count:   counts[i],
+			Width:   percent(counts[i], len(applications)),
+			Percent: fmt.Sprintf("%.0f%%", percent(counts[i], len(applications))),
+			Color:   statusColor(stage),
+		}
+		if i > 0 {
+			step.Rate = fmt.Sprintf("%.0f%%", percent(counts[i], counts[i-1]))
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// buildWeeks 按周（周一开始）统计邮件数，只保留最近 maxReportWeeks 周，没有邮件的周也占位
+func buildWeeks(records []types.JobApplication, width, height int) []weekBar {
+	counts := make(map[time.Time]int)
+	var first, last time.Time
+	for _, r := range records {
+		if r.Email.Date.IsZero() {
+			continue
+		}
+		week := weekStart(r.Email.Date)
+		counts[week]++
+		if first.IsZero() || week.Before(first) {
+			first = week
+		}
+		if week.After(last) {
+			last = week
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	if earliest := last.AddDate(0, 0, -7*(maxReportWeeks-1)); first.Before(earliest) {
+		first = earliest
+	}
+
+	var weeks []time.Time
+	max := 0
+	for week := first; !week.After(last); week = week.AddDate(0, 0, 7) {
+		weeks = append(weeks, week)
+		if counts[week] > max {
+			max = counts[week]
+		}
+	}
+
+	// 底部留出日期标签的高度
+	plotHeight := float64(height - 20)
+	slot := float64(width) / float64(len(weeks))
+	labelEvery := (len(weeks) + 7) / 8
+
+	var bars []weekBar
+	for i, week := range weeks {
+		h := plotHeight * float64(counts[week]) / float64(max)
+		bars = append(bars, weekBar{
+			Label:  week.Format("01-02"),
+			Count:  counts[week],
+			X:      float64(i)*slot + slot*0.1,
+			Y:      plotHeight - h,
+			W:      slot * 0.8,
+			H:      h,
+			LabelX: float64(i)*slot + slot/2,
+			Show:   i%labelEvery == 0,
+		})
+	}
+	return bars
+}
+
+// weekStart 所在周的周一零点
+func weekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func newAppRow(app store.Application) appRow {
+	row := appRow{
+		Company:    app.Company,
+		Position:   app.Position,
+		Status:     app.Status,
+		StatusName: statusName(app.Status),
+		Color:      statusColor(app.Status),
+		EmailCount: len(app.Emails),
+		Rounds:     app.Rounds(),
+		Flagged:    len(app.Flagged()),
+	}
+	if len(app.Emails) > 0 {
+		latest := app.Emails[len(app.Emails)-1]
+		row.First = app.Emails[0].Date.Format("2006-01-02")
+		row.Latest = latest.Date.Format("2006-01-02")
+		row.LatestValue = latest.Date.Unix()
+		row.Subject = latest.Subject
+	}
+
+	for _, e := range app.Emails {
+		row.Emails = append(row.Emails, emailLink{
+			Date:    e.Date.Format("2006-01-02"),
+			Subject: e.Subject,
+			Status:  e.Status,
+			Link:    messageLink(e.MessageID),
+		})
+	}
+	for _, t := range app.Timeline {
+		row.Steps = append(row.Steps, timelineStep{
+			Date:    t.At.Format("01-02"),
+			Status:  t.To,
+			Round:   t.Round,
+			Invalid: t.Invalid,
+			Note:    t.Note,
+			Color:   statusColor(t.To),
+		})
+	}
+	return row
+}
+
+// messageLink 由Message-ID生成 mid: 链接，邮件客户端可据此打开原邮件。
+// 没有Message-ID时申请库使用 sha1: 开头的替代键，不生成链接
+func messageLink(messageID string) template.URL {
+	id := strings.Trim(strings.TrimSpace(messageID), "<>")
+	if id == "" || strings.HasPrefix(messageID, "sha1:") {
+		return ""
+	}
+	return template.URL("mid:" + url.PathEscape(id))
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>JobTracker 求职报告</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; margin: 0; padding: 24px; color: #222; background: #f6f7f9; }
+h1 { margin: 0 0 4px; font-size: 24px; }
+h2 { font-size: 18px; margin: 0 0 12px; }
+.meta { color: #777; margin-bottom: 20px; }
+.cards { display: flex; gap: 12px; margin-bottom: 20px; flex-wrap: wrap; }
+.card { background: #fff; border-radius: 8px; padding: 12px 20px; box-shadow: 0 1px 3px rgba(0,0,0,.08); }
+.card b { display: block; font-size: 24px; }
+section { background: #fff; border-radius: 8px; padding: 16px 20px; margin-bottom: 20px; box-shadow: 0 1px 3px rgba(0,0,0,.08); }
+.grid { display: grid; grid-template-columns: repeat(auto-fit, minmax(320px, 1fr)); gap: 20px; }
+.grid section { margin-bottom: 0; }
+.bar-row { display: flex; align-items: center; margin: 6px 0; }
+.bar-label { width: 110px; flex-shrink: 0; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.bar-track { flex: 1; background: #eef0f3; border-radius: 4px; height: 22px; }
+.bar { height: 22px; border-radius: 4px; min-width: 2px; }
+.bar-value { width: 120px; text-align: right; color: #555; font-size: 13px; }
+svg text { font-size: 11px; fill: #666; }
+.company { margin-bottom: 12px; }
+.company h3 { font-size: 15px; margin: 0 0 6px; }
+.steps { display: flex; flex-wrap: wrap; align-items: center; gap: 4px; margin: 4px 0 4px 12px; font-size: 13px; }
+.position { width: 180px; color: #555; }
+.chip { color: #fff; border-radius: 10px; padding: 1px 8px; font-size: 12px; white-space: nowrap; }
+.chip.invalid { opacity: .5; text-decoration: line-through; }
+.filters { display: flex; gap: 8px; margin-bottom: 10px; }
+.filters input, .filters select { padding: 4px 8px; font-size: 14px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+th, td { border-bottom: 1px solid #eee; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { cursor: pointer; user-select: none; background: #fafafa; position: sticky; top: 0; }
+th.asc::after { content: " ▲"; } th.desc::after { content: " ▼"; }
+td.num { text-align: right; }
+details summary { cursor: pointer; color: #4e79a7; }
+details ul { margin: 4px 0; padding-left: 18px; }
+a { color: #4e79a7; }
+</style>
+</head>
+<body>
+<h1>JobTracker 求职报告</h1>
+<div class="meta">生成时间 {{.GeneratedAt}}</div>
+
+<div class="cards">
+  <div class="card"><b>{{.Applications}}</b>申请</div>
+  <div class="card"><b>{{.Emails}}</b>求职邮件</div>
+  <div class="card"><b>{{.Companies}}</b>公司</div>
+</div>
+
+<div class="grid">
+<section>
+  <h2>转化漏斗</h2>
+  {{range .Funnel}}
+  <div class="bar-row">
+    <div class="bar-label">{{.Label}}</div>
+    <div class="bar-track"><div class="bar" style="width: {{printf "%.1f" .Width}}%; background: {{.Color}}"></div></div>
+    <div class="bar-value">{{.Count}}（{{.Percent}}{{if .Rate}}，转化 {{.Rate}}{{end}}）</div>
+  </div>
+  {{end}}
+</section>
+
+<section>
+  <h2>状态分布（按邮件）</h2>
+  {{range .Statuses}}
+  <div class="bar-row">
+    <div class="bar-label">{{.Name}}</div>
+    <div class="bar-track"><div class="bar" style="width: {{printf "%.1f" .Width}}%; background: {{.Color}}"></div></div>
+    <div class="bar-value">{{.Count}} 封</div>
+  </div>
+  {{end}}
+</section>
+
+<section>
+  <h2>公司邮件数（前10名）</h2>
+  {{range .TopCompanies}}
+  <div class="bar-row">
+    <div class="bar-label" title="{{.Name}}">{{.Name}}</div>
+    <div class="bar-track"><div class="bar" style="width: {{printf "%.1f" .Width}}%; background: #76b7b2"></div></div>
+    <div class="bar-value">{{.Count}} 封</div>
+  </div>
+  {{end}}
+</section>
+</div>
+
+<section style="margin-top: 20px">
+  <h2>每周邮件数</h2>
+  {{if .Weeks}}
+  <svg viewBox="0 0 {{.ChartWidth}} {{.ChartHeight}}" width="100%" role="img">
+    {{range .Weeks}}
+    <rect x="{{printf "%.1f" .X}}" y="{{printf "%.1f" .Y}}" width="{{printf "%.1f" .W}}" height="{{printf "%.1f" .H}}" fill="#4e79a7"><title>{{.Label}} 当周: {{.Count}} 封</title></rect>
+    {{if .Show}}<text x="{{printf "%.1f" .LabelX}}" y="{{$.ChartHeight}}" text-anchor="middle">{{.Label}}</text>{{end}}
+    {{end}}
+  </svg>
+  {{else}}<p>没有邮件</p>{{end}}
+</section>
+
+<section>
+  <h2>各公司时间线</h2>
+  {{range .Groups}}
+  <div class="company">
+    <h3>{{.Company}}</h3>
+    {{range .Apps}}
+    <div class="steps">
+      <span class="position">{{.Position}}</span>
+      {{range $i, $s := .Steps}}{{if $i}}<span>→</span>{{end}}<span class="chip{{if $s.Invalid}} invalid{{end}}" style="background: {{$s.Color}}" title="{{$s.Note}}">{{$s.Date}} {{$s.Status}}{{if $s.Round}} #{{$s.Round}}{{end}}</span>{{end}}
+    </div>
+    {{end}}
+  </div>
+  {{end}}
+</section>
+
+<section>
+  <h2>申请列表</h2>
+  <div class="filters">
+    <input id="filter" type="search" placeholder="搜索公司、职位、主题">
+    <select id="status">
+      <option value="">全部状态</option>
+      <option>APPLIED</option><option>OA</option><option>INTERVIEW</option><option>OFFER</option><option>REJECTED</option><option>WITHDRAWN</option><option>OTHER</option>
+    </select>
+  </div>
+  <table id="apps">
+    <thead>
+      <tr><th>公司</th><th>职位</th><th>当前状态</th><th data-type="num">邮件数</th><th>首封邮件</th><th data-type="num">最近邮件</th><th data-type="num">面试轮次</th><th>邮件</th></tr>
+    </thead>
+    <tbody>
+      {{range .Rows}}
+      <tr data-status="{{.Status}}">
+        <td>{{.Company}}</td>
+        <td>{{.Position}}</td>
+        <td><span class="chip" style="background: {{.Color}}">{{.Status}}</span>{{if .Flagged}} ⚠️{{end}}</td>
+        <td class="num">{{.EmailCount}}</td>
+        <td>{{.First}}</td>
+        <td data-value="{{.LatestValue}}">{{.Latest}}</td>
+        <td class="num">{{.Rounds}}</td>
+        <td>
+          <details>
+            <summary>{{.Subject}}</summary>
+            <ul>
+              {{range .Emails}}<li>{{.Date}} [{{.Status}}] {{if .Link}}<a href="{{.Link}}">{{.Subject}}</a>{{else}}{{.Subject}}{{end}}</li>{{end}}
+            </ul>
+          </details>
+        </td>
+      </tr>
+      {{end}}
+    </tbody>
+  </table>
+</section>
+
+<script>
+(function () {
+  var table = document.getElementById("apps");
+  var tbody = table.tBodies[0];
+  var filter = document.getElementById("filter");
+  var status = document.getElementById("status");
+
+  function applyFilter() {
+    var q = filter.value.toLowerCase();
+    var s = status.value;
+    Array.prototype.forEach.call(tbody.rows, function (row) {
+      var ok = (!s || row.dataset.status === s) && (!q || row.textContent.toLowerCase().indexOf(q) >= 0);
+      row.style.display = ok ? "" : "none";
+    });
+  }
+  filter.addEventListener("input", applyFilter);
+  status.addEventListener("change", applyFilter);
+
+  Array.prototype.forEach.call(table.tHead.rows[0].cells, function (th, col) {
+    th.addEventListener("click", function () {
+      var desc = th.classList.contains("asc");
+      Array.prototype.forEach.call(th.parentNode.cells, function (c) { c.classList.remove("asc", "desc"); });
+      th.classList.add(desc ? "desc" : "asc");
+
+      var numeric = th.dataset.type === "num";
+      var rows = Array.prototype.slice.call(tbody.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[col], y = b.cells[col];
+        var vx = x.dataset.value || x.textContent.trim(), vy = y.dataset.value || y.textContent.trim();
+        var r = numeric ? Number(vx) - Number(vy) : vx.localeCompare(vy, "zh-CN");
+        return desc ? -r : r;
+      });
+      rows.forEach(function (row) { tbody.appendChild(row); });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,215 @@
+package exporter
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// iCalendar导出器（RFC 5545）
+type ICSExporter struct {
+	filename string
+}
+
+// 创建iCalendar导出器
+func NewICSExporter(filename string) *ICSExporter {
+	return &ICSExporter{
+		filename: filename,
+	}
+}
+
+// 把跟进提醒导出为全天事件，UID 由申请键生成，重复导出后日历订阅会更新而不是重复
+func (ie *ICSExporter) ExportFollowUps(followUps []store.FollowUp) error {
+	cal := newCalendar()
+	for _, f := range followUps {
+		app := f.Application
+		summary := fmt.Sprintf("跟进: %s - %s", app.Company, app.Position)
+		if f.State == store.FollowUpGhosted {
+			summary = fmt.Sprintf("可能已无回音: %s - %s", app.Company, app.Position)
+		}
+
+		cal.event(
+			eventUID("followup", app.Key),
+			summary,
+			fmt.Sprintf("当前状态 %s，最近一封邮件 %s（%d 天前）。\n最近邮件主题: %s",
+				app.Status, f.LastActivity.Format("2006-01-02"), f.IdleDays, app.Latest().Subject),
+			"DTSTART;VALUE=DATE:"+f.SuggestedDate.Format("20060102"),
+			"DTEND;VALUE=DATE:"+f.SuggestedDate.AddDate(0, 0, 1).Format("20060102"),
+		)
+	}
+	return cal.writeFile(ie.filename)
+}
+
+// 把笔试/面试安排和截止时间导出为事件，可在日历应用中订阅。
+// 同一日历邀请（相同UID）改期时只保留最近一封邮件中的时间
+func (ie *ICSExporter) ExportEvents(apps []store.Application) error {
+	cal := newCalendar()
+	for _, app := range apps {
+		var keys []string
+		events := make(map[string]store.LinkedEmail)
+		for _, e := range app.Emails {
+			if e.EventStart.IsZero() && e.Deadline.IsZero() {
+				continue
+			}
+			key := e.EventUID
+			if key == "" {
+				key = e.MessageID
+			}
+			prev, ok := events[key]
+			if !ok {
+				keys = append(keys, key)
+			} else if e.MeetingURL == "" {
+				e.MeetingURL = prev.MeetingURL
+			}
+			events[key] = e // 邮件按时间排序，后面的覆盖前面的
+		}
+
+		for _, key := range keys {
+			e := events[key]
+			description := strings.TrimSpace(e.Description + "\n邮件主题: " + e.Subject)
+
+			if !e.EventStart.IsZero() {
+				end := e.EventEnd
+				if end.IsZero() {
+					end = e.EventStart.Add(time.Hour)
+				}
+				props := []string{
+					"DTSTART:" + icsTime(e.EventStart),
+					"DTEND:" + icsTime(end),
+				}
+				if link, ok := meetingURL(e.MeetingURL); ok {
+					props = append(props, "LOCATION:"+icsEscape(link), "URL:"+link)
+				}
+				cal.event(
+					eventUID("event", app.Key+"|"+key),
+					fmt.Sprintf("%s: %s - %s", eventLabel(e.Status), app.Company, app.Position),
+					description,
+					props...,
+				)
+			}
+
+			if !e.Deadline.IsZero() {
+				cal.event(
+					eventUID("deadline", app.Key+"|"+key),
+					fmt.Sprintf("截止: %s - %s", app.Company, app.Position),
+					description,
+					"DTSTART:"+icsTime(e.Deadline),
+					"DTEND:"+icsTime(e.Deadline),
+				)
+			}
+		}
+	}
+	return cal.writeFile(ie.filename)
+}
+
+// meetingURL 校验会议链接。链接来自邮件内容，URL 属性值不转义，
+// 含控制字符（如CR/LF，可注入额外的属性行）或不是 http(s) 绝对地址时不导出
+func meetingURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.ContainsFunc(raw, unicode.IsControl) {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// eventLabel 事件标题前缀
+func eventLabel(status types.Status) string {
+	switch status {
+	case types.StatusOA:
+		return "笔试"
+	case types.StatusInterview:
+		return "面试"
+	default:
+		return "日程"
+	}
+}
+
+// icsTime UTC格式的DATE-TIME，避免在文件中附带VTIMEZONE定义
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// calendar 按行构建VCALENDAR，行以CRLF结尾并按75字节折行
+type calendar struct {
+	lines []string
+}
+
+func newCalendar() *calendar {
+	return &calendar{lines: []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//JobTracker//JobTracker//ZH",
+		"CALSCALE:GREGORIAN",
+	}}
+}
+
+// event 添加一个事件，props 为已格式化的时间等属性行
+func (c *calendar) event(uid, summary, description string, props ...string) {
+	c.lines = append(c.lines,
+		"BEGIN:VEVENT",
+		"UID:"+icsEscape(uid)+"@jobtracker",
+		"DTSTAMP:"+icsTime(time.Now()),
+		"SUMMARY:"+icsEscape(summary),
+	)
+	if description != "" {
+		c.lines = append(c.lines, "DESCRIPTION:"+icsEscape(description))
+	}
+	c.lines = append(c.lines, props...)
+	c.lines = append(c.lines, "END:VEVENT")
+}
+
+func (c *calendar) writeFile(filename string) error {
+	var b strings.Builder
+	for _, line := range append(c.lines, "END:VCALENDAR") {
+		b.WriteString(foldLine(line))
+		b.WriteString("\r\n")
+	}
+
+	if err := os.WriteFile(filename, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("write ICS file: %w", err)
+	}
+	return nil
+}
+
+// eventUID 由稳定的键生成事件UID，同一申请每次导出的UID相同
+func eventUID(kind, key string) string {
+	h := sha1.Sum([]byte(key))
+	return kind + "-" + hex.EncodeToString(h[:8])
+}
+
+// icsEscape 转义TEXT类型属性值中的特殊字符
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// foldLine 超过75字节的行折行，续行以空格开头，不拆分UTF-8字符
+func foldLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+func TestMeetingURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+		ok   bool
+	}{
+		{"https://zoom.us/j/123?pwd=abc", "https://zoom.us/j/123?pwd=abc", true},
+		{"  https://meet.google.com/abc-defg-hij ", "https://meet.google.com/abc-defg-hij", true},
+		{"http://example.com/room", "http://example.com/room", true},
+		{"", "", false},
+		{"https://zoom.us/j/1\r\nATTENDEE:mailto:attacker@example.com", "", false},
+		{"https://zoom.us/j/1\nX", "", false},
+		{"https://zoom.us/j/1\x00", "", false},
+		{"javascript:alert(1)", "", false},
+		{"zoom.us/j/123", "", false},
+		{"https:///path", "", false},
+		{"https://exa mple.com", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := meetingURL(tt.raw)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("meetingURL(%q) = %q, %v; want %q, %v", tt.raw, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExportEventsRejectsInjectedURL(t *testing.T) {
+	start := time.Date(2025, 3, 15, 10, 0, 0, 0, time.UTC)
+	apps := []store.Application{
+		{
+			Key: "acme|engineer", Company: "Acme", Position: "Engineer",
+			Emails: []store.LinkedEmail{{
+				MessageID:  "<1@acme.com>",
+				Subject:    "Interview",
+				Status:     types.StatusInterview,
+				EventStart: start,
+				MeetingURL: "https://zoom.us/j/1\r\nATTENDEE:mailto:attacker@example.com",
+			}},
+		},
+		{
+			Key: "globex|analyst", Company: "Globex", Position: "Analyst",
+			Emails: []store.LinkedEmail{{
+				MessageID:  "<2@globex.com>",
+				Subject:    "Interview",
+				Status:     types.StatusInterview,
+				EventStart: start,
+				MeetingURL: "https://meet.google.com/abc-defg-hij",
+			}},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "events.ics")
+	if err := NewICSExporter(path).ExportEvents(apps); err != nil {
+		t.Fatalf("ExportEvents: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+
+	if strings.Contains(out, "ATTENDEE") || strings.Contains(out, "zoom.us") {
+		t.Errorf("invalid meeting URL exported:\n%s", out)
+	}
+	if !strings.Contains(out, "\r\nURL:https://meet.google.com/abc-defg-hij\r\n") {
+		t.Errorf("valid meeting URL missing:\n%s", out)
+	}
+	if n := strings.Count(out, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("got %d events, want 2", n)
+	}
+}
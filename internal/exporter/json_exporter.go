@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+)
+
+func init() {
+	Register("json", func() Exporter { return JSONExporter{} })
+	Register("ndjson", func() Exporter { return NDJSONExporter{} })
+}
+
+// JSONExporter 导出为一个JSON数组，字段与申请库一致
+type JSONExporter struct{}
+
+func (JSONExporter) Extension() string { return ".json" }
+
+func (JSONExporter) Write(w io.Writer, applications []store.Application) error {
+	if applications == nil {
+		applications = []store.Application{} // 输出 [] 而不是 null
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(applications); err != nil {
+		return fmt.Errorf("write JSON: %w", err)
+	}
+	return nil
+}
+
+// NDJSONExporter 每行一个申请，便于用 jq 等工具流式处理
+type NDJSONExporter struct{}
+
+func (NDJSONExporter) Extension() string { return ".ndjson" }
+
+func (NDJSONExporter) Write(w io.Writer, applications []store.Application) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, app := range applications {
+		if err := enc.Encode(app); err != nil {
+			return fmt.Errorf("write NDJSON: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+func init() {
+	Register("markdown", func() Exporter { return MarkdownExporter{} })
+	Register("md", func() Exporter { return MarkdownExporter{} })
+}
+
+// MarkdownExporter 导出为Markdown表格，便于贴到周报
+type MarkdownExporter struct{}
+
+func (MarkdownExporter) Extension() string { return ".md" }
+
+func (MarkdownExporter) Write(w io.Writer, applications []store.Application) error {
+	var b strings.Builder
+
+	// 状态汇总，按流程顺序
+	counts := make(map[types.Status]int)
+	for _, app := range applications {
+		counts[app.Status]++
+	}
+	var summary []string
+	for _, status := range []types.Status{types.StatusApplied, types.StatusOA, types.StatusInterview,
+		types.StatusOffer, types.StatusRejected, types.StatusWithdrawn, types.StatusOther} {
+		if counts[status] > 0 {
+			summary = append(summary, fmt.Sprintf("%s %d", status, counts[status]))
+		}
+	}
+	fmt.Fprintf(&b, "共 %d 个申请", len(applications))
+	if len(summary) > 0 {
+		b.WriteString("：" + strings.Join(summary, "，"))
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString("| 公司 | 职位 | 当前状态 | 面试轮次 | 最近邮件日期 | 最近邮件主题 |\n")
+	b.WriteString("| --- | --- | --- | ---: | --- | --- |\n")
+	for _, app := range applications {
+		date, subject := "", ""
+		if latest := app.Latest(); latest != nil {
+			date, subject = latest.Date.Format("2006-01-02"), latest.Subject
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+			markdownCell(app.Company), markdownCell(app.Position), app.Status,
+			strconv.Itoa(app.Rounds()), date, markdownCell(subject))
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("write Markdown: %w", err)
+	}
+	return nil
+}
+
+// markdownCell 转义表格单元格中的竖线和换行
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
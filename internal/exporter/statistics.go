@@ -0,0 +1,117 @@
+package exporter
+
+import (
+	"sort"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// 状态的中文名称
+var statusNames = map[types.Status]string{
+	types.StatusApplied:   "已申请",
+	types.StatusOA:        "在线测试/笔试",
+	types.StatusInterview: "面试",
+	types.StatusOffer:     "收到Offer",
+	types.StatusRejected:  "被拒绝",
+	types.StatusWithdrawn: "撤回申请",
+	types.StatusOther:     "其他状态",
+}
+
+// 状态的展示顺序
+var statusOrder = []types.Status{
+	types.StatusApplied, types.StatusOA, types.StatusInterview, types.StatusOffer,
+	types.StatusRejected, types.StatusWithdrawn, types.StatusOther,
+}
+
+func statusName(status types.Status) string {
+	if name := statusNames[status]; name != "" {
+		return name
+	}
+	return string(status)
+}
+
+// statistics 按邮件统计的状态分布和公司分布
+type statistics struct {
+	total       int
+	statusCount map[types.Status]int
+	// 按邮件数从多到少排列，数量相同时按公司名排列
+	companies []companyCount
+}
+
+type companyCount struct {
+	name  string
+	count int
+}
+
+// computeStatistics 统计各状态和各公司的邮件数，公司名为空的不计入公司分布
+func computeStatistics(applications []types.JobApplication) statistics {
+	stats := statistics{
+		total:       len(applications),
+		statusCount: make(map[types.Status]int),
+	}
+
+	companyIndex := make(map[string]int)
+	for _, app := range applications {
+		stats.statusCount[app.Status]++
+		if app.Company == "" {
+			continue
+		}
+		if i, ok := companyIndex[app.Company]; ok {
+			stats.companies[i].count++
+		} else {
+			companyIndex[app.Company] = len(stats.companies)
+			stats.companies = append(stats.companies, companyCount{app.Company, 1})
+		}
+	}
+
+	sort.SliceStable(stats.companies, func(i, j int) bool {
+		if stats.companies[i].count != stats.companies[j].count {
+			return stats.companies[i].count > stats.companies[j].count
+		}
+		return stats.companies[i].name < stats.companies[j].name
+	})
+	return stats
+}
+
+// topCompanies 邮件最多的前 n 家公司
+func (s statistics) topCompanies(n int) []companyCount {
+	if len(s.companies) < n {
+		return s.companies
+	}
+	return s.companies[:n]
+}
+
+// statuses 按展示顺序返回出现过的状态
+func (s statistics) statuses() []types.Status {
+	var result []types.Status
+	for _, status := range statusOrder {
+		if s.statusCount[status] > 0 {
+			result = append(result, status)
+		}
+	}
+	return result
+}
+
+// EmailRecords 把申请库中的申请展开为按邮件的记录，可用于 PrintJobStatistics 和 ExportStatistics
+func EmailRecords(applications []store.Application) []types.JobApplication {
+	var records []types.JobApplication
+	for _, app := range applications {
+		for _, e := range app.Emails {
+			records = append(records, types.JobApplication{
+				Company:     app.Company,
+				Position:    app.Position,
+				Status:      e.Status,
+				Description: e.Description,
+				Email: types.Email{
+					From:      e.From,
+					Subject:   e.Subject,
+					Date:      e.Date,
+					MessageID: e.MessageID,
+					Folder:    e.Folder,
+				},
+			})
+		}
+	}
+	return records
+}
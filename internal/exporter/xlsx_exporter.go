@@ -0,0 +1,401 @@
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+func init() {
+	Register("xlsx", func() Exporter { return XLSXExporter{} })
+}
+
+// XLSXExporter 导出为Excel工作簿（Office Open XML，直接用 archive/zip 写出），
+// 包含 Applications、Emails、Status Statistics 和 Company Statistics 四个工作表。
+// 日期为真正的日期单元格，表头冻结并带筛选，状态列按状态着色
+type XLSXExporter struct{}
+
+func (XLSXExporter) Extension() string { return ".xlsx" }
+
+// styles.xml 中 cellXfs 的序号
+const (
+	xlsxStyleDefault  = 0
+	xlsxStyleHeader   = 1
+	xlsxStyleDateTime = 2
+	xlsxStyleDate     = 3
+)
+
+// 状态列条件格式使用的颜色，与 styles.xml 中 dxfs 的顺序一致
+var xlsxStatusFills = []struct {
+	status     types.Status
+	fill, font string
+}{
+	{types.StatusApplied, "FFDCE6F2", "FF1F3864"},
+	{types.StatusOA, "FFFCE4D6", "FF843C0C"},
+	{types.StatusInterview, "FFE4DFEC", "FF5B3A73"},
+	{types.StatusOffer, "FFE2EFDA", "FF375623"},
+	{types.StatusRejected, "FFFADBD8", "FF9C0006"},
+	{types.StatusWithdrawn, "FFEDEDED", "FF595959"},
+}
+
+// xlsxCell 一个单元格，按 value 的类型写成字符串、数字或日期
+type xlsxCell struct {
+	value interface{}
+	style int
+}
+
+type xlsxSheet struct {
+	name   string
+	header []string
+	widths []float64
+	rows   [][]xlsxCell
+	// 需要按状态着色的列（从0开始），-1 表示没有
+	statusCol int
+}
+
+func (XLSXExporter) Write(w io.Writer, applications []store.Application) error {
+	sheets := []xlsxSheet{
+		applicationsSheet(applications),
+		emailsSheet(applications),
+		statusSheet(applications),
+		companySheet(applications),
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes(len(sheets))},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook(sheets)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(sheets))},
+		{"xl/styles.xml", xlsxStyles()},
+	}
+	for i, sheet := range sheets {
+		files = append(files, struct {
+			name    string
+			content string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()})
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return fmt.Errorf("write XLSX: %w", err)
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return fmt.Errorf("write XLSX: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("write XLSX: %w", err)
+	}
+	return nil
+}
+
+// applicationsSheet 每个申请一行，列与CSV导出一致
+func applicationsSheet(applications []store.Application) xlsxSheet {
+	sheet := xlsxSheet{
+		name: "Applications",
+		header: []string{"公司名称", "职位名称", "职位编号", "当前状态", "工作地点", "状态描述", "邮件数量",
+			"首封邮件日期", "最近邮件日期", "最近邮件主题", "面试轮次", "状态时间线", "异常状态变化"},
+		widths:    []float64{20, 24, 14, 12, 14, 30, 10, 18, 18, 40, 10, 50, 12},
+		statusCol: 3,
+	}
+
+	for _, app := range applications {
+		var first, latest store.LinkedEmail
+		if len(app.Emails) > 0 {
+			first, latest = app.Emails[0], app.Emails[len(app.Emails)-1]
+		}
+		sheet.rows = append(sheet.rows, []xlsxCell{
+			{value: app.Company},
+			{value: app.Position},
+			{value: app.RequisitionID},
+			{value: string(app.Status)},
+			{value: app.Location},
+			{value: latest.Description},
+			{value: len(app.Emails)},
+			{value: first.Date, style: xlsxStyleDateTime},
+			{value: latest.Date, style: xlsxStyleDateTime},
+			{value: latest.Subject},
+			{value: app.Rounds()},
+			{value: app.TimelineString()},
+			{value: len(app.Flagged())},
+		})
+	}
+	return sheet
+}
+
+// emailsSheet 每封邮件一行
+func emailsSheet(applications []store.Application) xlsxSheet {
+	sheet := xlsxSheet{
+		name:      "Emails",
+		header:    []string{"公司名称", "职位名称", "状态", "邮件日期", "发件人", "邮件主题", "邮件文件夹", "状态描述", "Message-ID"},
+		widths:    []float64{20, 24, 12, 18, 28, 40, 14, 30, 40},
+		statusCol: 2,
+	}
+
+	for _, app := range applications {
+		for _, e := range app.Emails {
+			sheet.rows = append(sheet.rows, []xlsxCell{
+				{value: app.Company},
+				{value: app.Position},
+				{value: string(e.Status)},
+				{value: e.Date, style: xlsxStyleDateTime},
+				{value: e.From},
+				{value: e.Subject},
+				{value: e.Folder},
+				{value: e.Description},
+				{value: e.MessageID},
+			})
+		}
+	}
+	return sheet
+}
+
+// statusSheet 各状态的邮件数（与命令行统计一致）和处于该状态的申请数
+func statusSheet(applications []store.Application) xlsxSheet {
+	sheet := xlsxSheet{
+		name:      "Status Statistics",
+		header:    []string{"状态", "状态名称", "邮件数", "当前处于该状态的申请数"},
+		widths:    []float64{12, 16, 10, 24},
+		statusCol: 0,
+	}
+
+	stats := computeStatistics(EmailRecords(applications))
+	current := make(map[types.Status]int)
+	for _, app := range applications {
+		current[app.Status]++
+	}
+
+	for _, status := range statusOrder {
+		if stats.statusCount[status] == 0 && current[status] == 0 {
+			continue
+		}
+		sheet.rows = append(sheet.rows, []xlsxCell{
+			{value: string(status)},
+			{value: statusName(status)},
+			{value: stats.statusCount[status]},
+			{value: current[status]},
+		})
+	}
+	return sheet
+}
+
+// companySheet 各公司的邮件数和申请数，按邮件数排序
+func companySheet(applications []store.Application) xlsxSheet {
+	sheet := xlsxSheet{
+		name:      "Company Statistics",
+		header:    []string{"公司名称", "邮件数", "申请数", "最近邮件日期"},
+		widths:    []float64{24, 10, 10, 18},
+		statusCol: -1,
+	}
+
+	appCount := make(map[string]int)
+	latest := make(map[string]time.Time)
+	for _, app := range applications {
+		appCount[app.Company]++
+		if l := app.Latest(); l != nil && l.Date.After(latest[app.Company]) {
+			latest[app.Company] = l.Date
+		}
+	}
+
+	for _, c := range computeStatistics(EmailRecords(applications)).companies {
+		sheet.rows = append(sheet.rows, []xlsxCell{
+			{value: c.name},
+			{value: c.count},
+			{value: appCount[c.name]},
+			{value: latest[c.name], style: xlsxStyleDate},
+		})
+	}
+	return sheet
+}
+
+// xml 生成工作表XML：冻结首行、表头筛选、状态列条件格式
+func (s xlsxSheet) xml() string {
+	var b strings.Builder
+	lastCol := xlsxColumn(len(s.header) - 1)
+	lastRow := len(s.rows) + 1
+
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	fmt.Fprintf(&b, `<dimension ref="A1:%s%d"/>`, lastCol, lastRow)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/><selection pane="bottomLeft"/></sheetView></sheetViews>`)
+
+	b.WriteString(`<cols>`)
+	for i, width := range s.widths {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+	}
+	b.WriteString(`</cols>`)
+
+	b.WriteString(`<sheetData>`)
+	header := make([]xlsxCell, len(s.header))
+	for i, h := range s.header {
+		header[i] = xlsxCell{value: h, style: xlsxStyleHeader}
+	}
+	writeXLSXRow(&b, 1, header)
+	for i, row := range s.rows {
+		writeXLSXRow(&b, i+2, row)
+	}
+	b.WriteString(`</sheetData>`)
+
+	fmt.Fprintf(&b, `<autoFilter ref="A1:%s%d"/>`, lastCol, lastRow)
+
+	if s.statusCol >= 0 && len(s.rows) > 0 {
+		col := xlsxColumn(s.statusCol)
+		fmt.Fprintf(&b, `<conditionalFormatting sqref="%s2:%s%d">`, col, col, lastRow)
+		for i, f := range xlsxStatusFills {
+			fmt.Fprintf(&b, `<cfRule type="cellIs" dxfId="%d" priority="%d" operator="equal"><formula>"%s"</formula></cfRule>`,
+				i, i+1, f.status)
+		}
+		b.WriteString(`</conditionalFormatting>`)
+	}
+
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+func writeXLSXRow(b *strings.Builder, row int, cells []xlsxCell) {
+	fmt.Fprintf(b, `<row r="%d">`, row)
+	for i, cell := range cells {
+		ref := xlsxColumn(i) + strconv.Itoa(row)
+		style := ""
+		if cell.style != xlsxStyleDefault {
+			style = fmt.Sprintf(` s="%d"`, cell.style)
+		}
+
+		switch v := cell.value.(type) {
+		case int:
+			fmt.Fprintf(b, `<c r="%s"%s><v>%d</v></c>`, ref, style, v)
+		case time.Time:
+			if v.IsZero() {
+				fmt.Fprintf(b, `<c r="%s"%s/>`, ref, style)
+			} else {
+				fmt.Fprintf(b, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(excelSerial(v), 'f', -1, 64))
+			}
+		case string:
+			if v == "" {
+				continue
+			}
+			fmt.Fprintf(b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xlsxEscape(v))
+		}
+	}
+	b.WriteString(`</row>`)
+}
+
+// excelSerial Excel日期序列值（1900日期系统），按时间本身的时区取年月日时分
+func excelSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return wall.Sub(epoch).Hours() / 24
+}
+
+// xlsxColumn 列序号（从0开始）转为列名 A、B…Z、AA…
+func xlsxColumn(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+// xlsxEscape 转义XML特殊字符并去掉XML 1.0不允许的控制字符
+func xlsxEscape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, s)
+
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func xlsxContentTypes(sheets int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+func xlsxWorkbook(sheets []xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(sheet.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets><definedNames>`)
+	// Excel 用隐藏的 _FilterDatabase 名称记录每个工作表的筛选范围
+	for i, sheet := range sheets {
+		fmt.Fprintf(&b, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">'%s'!$A$1:$%s$%d</definedName>`,
+			i, xlsxEscape(sheet.name), xlsxColumn(len(sheet.header)-1), len(sheet.rows)+1)
+	}
+	b.WriteString(`</definedNames></workbook>`)
+	return b.String()
+}
+
+func xlsxWorkbookRels(sheets int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// xlsxStyles 单元格样式（默认、表头、日期时间、日期）和状态列条件格式的差异样式
+func xlsxStyles() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm"/></numFmts>`)
+	b.WriteString(`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>`)
+	b.WriteString(`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+		`<fill><patternFill patternType="solid"><fgColor rgb="FFD9E1F2"/><bgColor indexed="64"/></patternFill></fill></fills>`)
+	b.WriteString(`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`)
+	b.WriteString(`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`)
+	b.WriteString(`<cellXfs count="4">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`</cellXfs>`)
+	b.WriteString(`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>`)
+
+	fmt.Fprintf(&b, `<dxfs count="%d">`, len(xlsxStatusFills))
+	for _, f := range xlsxStatusFills {
+		fmt.Fprintf(&b, `<dxf><font><color rgb="%s"/></font><fill><patternFill patternType="solid"><bgColor rgb="%s"/></patternFill></fill></dxf>`, f.font, f.fill)
+	}
+	b.WriteString(`</dxfs>`)
+
+	b.WriteString(`</styleSheet>`)
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/store"
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// worksheet 测试用到的工作表结构
+type worksheet struct {
+	Pane struct {
+		YSplit      string `xml:"ySplit,attr"`
+		TopLeftCell string `xml:"topLeftCell,attr"`
+		State       string `xml:"state,attr"`
+	} `xml:"sheetViews>sheetView>pane"`
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Style  string `xml:"s,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+	AutoFilter struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"autoFilter"`
+}
+
+func TestXLSXWorkbook(t *testing.T) {
+	applied := time.Date(2025, 3, 1, 9, 30, 0, 0, time.FixedZone("CST", 8*3600))
+	interview := time.Date(2025, 3, 10, 18, 0, 0, 0, time.UTC)
+	apps := []store.Application{
+		{
+			Key: "acme|engineer", Company: "Acme & <Sons>", Position: "Engineer\x01", Status: types.StatusInterview,
+			Emails: []store.LinkedEmail{
+				{MessageID: "a1", Subject: "Thanks for applying", Date: applied, Status: types.StatusApplied},
+				{MessageID: "a2", Subject: "Interview \"invite\"", Date: interview, Status: types.StatusInterview},
+			},
+		},
+		{
+			Key: "globex|analyst", Company: "Globex", Position: "Analyst", Status: types.StatusApplied,
+			Emails: []store.LinkedEmail{{MessageID: "g1", Subject: "Received", Date: applied, Status: types.StatusApplied}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (XLSXExporter{}).Write(&buf, apps); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open workbook: %v", err)
+	}
+
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = data
+
+		// 每个部件都必须是格式正确的XML
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := dec.Token(); err != nil {
+				if !errors.Is(err, io.EOF) {
+					t.Errorf("%s is not well-formed: %v", f.Name, err)
+				}
+				break
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels",
+		"xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml", "xl/worksheets/sheet4.xml"} {
+		if parts[name] == nil {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	sheets := make([]worksheet, 4)
+	for i := range sheets {
+		if err := xml.Unmarshal(parts["xl/worksheets/sheet"+strconv.Itoa(i+1)+".xml"], &sheets[i]); err != nil {
+			t.Fatalf("parse sheet%d: %v", i+1, err)
+		}
+		pane := sheets[i].Pane
+		if pane.YSplit != "1" || pane.TopLeftCell != "A2" || pane.State != "frozen" {
+			t.Errorf("sheet%d pane = %+v, want the header row frozen", i+1, pane)
+		}
+	}
+
+	apps1 := sheets[0]
+	if apps1.AutoFilter.Ref != "A1:M3" || len(apps1.Rows) != 3 {
+		t.Errorf("Applications: autofilter %s with %d rows, want A1:M3 and 3 rows", apps1.AutoFilter.Ref, len(apps1.Rows))
+	}
+	cells := make(map[string]string)
+	styles := make(map[string]string)
+	for _, row := range apps1.Rows {
+		for _, c := range row.Cells {
+			cells[c.Ref] = c.Value + c.Inline
+			styles[c.Ref] = c.Style
+		}
+	}
+	if cells["A2"] != "Acme & <Sons>" || cells["B2"] != "Engineer" {
+		t.Errorf("text cells = %q, %q", cells["A2"], cells["B2"])
+	}
+
+	// 日期按邮件自身时区的年月日时分写成序列值：2025-03-01 09:30 → 45717.395833…
+	for ref, want := range map[string]float64{"H2": 45717 + 9.5/24, "I2": 45726 + 18.0/24} {
+		got, err := strconv.ParseFloat(cells[ref], 64)
+		if err != nil || math.Abs(got-want) > 1e-6 {
+			t.Errorf("%s = %q, want serial %f", ref, cells[ref], want)
+		}
+		if styles[ref] != strconv.Itoa(xlsxStyleDateTime) {
+			t.Errorf("%s style = %q, want date-time style", ref, styles[ref])
+		}
+	}
+	if cells["G2"] != "2" || cells["D2"] != "INTERVIEW" {
+		t.Errorf("email count %q, status %q", cells["G2"], cells["D2"])
+	}
+
+	// 工作簿为每个工作表定义筛选范围
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+		Defined []struct {
+			Sheet string `xml:"localSheetId,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"definedNames>definedName"`
+	}
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatal(err)
+	}
+	if len(workbook.Sheets) != 4 || len(workbook.Defined) != 4 {
+		t.Fatalf("workbook has %d sheets and %d defined names", len(workbook.Sheets), len(workbook.Defined))
+	}
+	if want := "'Applications'!$A$1:$M$3"; workbook.Defined[0].Value != want {
+		t.Errorf("filter range = %q, want %q", workbook.Defined[0].Value, want)
+	}
+	if !strings.Contains(string(parts["xl/styles.xml"]), `<dxfs count="6">`) {
+		t.Error("styles.xml missing the status dxfs")
+	}
+}
@@ -0,0 +1,225 @@
+package mailcache
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// Cache 本地原始邮件缓存，每封邮件一个 gzip 压缩的JSON文件，
+// 文件名为去重键（Message-ID）的 SHA-256，按前两位分目录：<dir>/ab/abcdef….json.gz
+type Cache struct {
+	dir string
+}
+
+// entry 缓存文件的内容
+type entry struct {
+	Key       string      `json:"key"`
+	FetchedAt time.Time   `json:"fetched_at"`
+	Email     types.Email `json:"email"`
+}
+
+// Retention 清理策略，零值表示不限制
+type Retention struct {
+	MaxAge      time.Duration // 按最后一次获取的时间，早于 now-MaxAge 获取的邮件被清理
+	MaxMessages int           // 超过时按邮件日期清理最早的邮件
+	// 在此之后获取的邮件（如本次获取的）不会被清理，补抓的旧邮件不会刚保存就被删除
+	KeepFetchedSince time.Time
+}
+
+const fileExt = ".json.gz"
+
+// Open 打开（必要时创建）缓存目录
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create mail cache dir: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Dir 缓存目录
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name+fileExt)
+}
+
+// Put 保存一封邮件，已缓存的同一封邮件（按 types.Email.Key）以新获取的为准。added 表示之前没有缓存
+func (c *Cache) Put(email types.Email) (added bool, err error) {
+	key := email.Key()
+	path := c.path(key)
+
+	_, err = os.Stat(path)
+	added = errors.Is(err, os.ErrNotExist)
+
+	if err := writeEntry(path, entry{Key: key, FetchedAt: time.Now(), Email: email}); err != nil {
+		return false, err
+	}
+	return added, nil
+}
+
+// writeEntry 先写临时文件再重命名，中断时不会留下损坏的缓存文件
+func writeEntry(path string, e entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create mail cache dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write cached mail: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	err = json.NewEncoder(zw).Encode(e)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write cached mail: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write cached mail: %w", err)
+	}
+	return nil
+}
+
+// Get 按去重键读取邮件，没有缓存时返回 nil
+func (c *Cache) Get(key string) (*types.Email, error) {
+	e, err := readEntry(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e.Email, nil
+}
+
+// List 返回所有缓存的邮件，按邮件日期从早到晚排列
+func (c *Cache) List() ([]types.Email, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	emails := make([]types.Email, len(entries))
+	for i, e := range entries {
+		emails[i] = e.Email
+	}
+	return emails, nil
+}
+
+// Prune 按清理策略删除邮件，返回删除的数量
+func (c *Cache) Prune(policy Retention, now time.Time) (int, error) {
+	if policy.MaxAge <= 0 && policy.MaxMessages <= 0 {
+		return 0, nil
+	}
+
+	entries, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+
+	var evict []entry
+	if policy.MaxAge > 0 {
+		cutoff := now.Add(-policy.MaxAge)
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.FetchedAt.Before(cutoff) && !policy.keep(e) {
+				evict = append(evict, e)
+			} else {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+	// entries 按邮件日期从早到晚排列，跳过受保护的邮件删除最早的
+	excess := len(entries) - policy.MaxMessages
+	for _, e := range entries {
+		if policy.MaxMessages <= 0 || excess <= 0 {
+			break
+		}
+		if !policy.keep(e) {
+			evict = append(evict, e)
+			excess--
+		}
+	}
+
+	for _, e := range evict {
+		if err := os.Remove(c.path(e.Key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("evict cached mail: %w", err)
+		}
+	}
+	return len(evict), nil
+}
+
+func (p Retention) keep(e entry) bool {
+	return !p.KeepFetchedSince.IsZero() && !e.FetchedAt.Before(p.KeepFetchedSince)
+}
+
+// entries 读取所有缓存文件，按邮件日期从早到晚排列。无法读取的文件记录日志后跳过
+func (c *Cache) entries() ([]entry, error) {
+	var entries []entry
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, fileExt) {
+			return nil
+		}
+		e, err := readEntry(path)
+		if err != nil {
+			log.Printf("跳过无法读取的缓存邮件: %v", err)
+			return nil
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read mail cache: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Email.Date.Before(entries[j].Email.Date) })
+	return entries, nil
+}
+
+func readEntry(path string) (entry, error) {
+	var e entry
+	f, err := os.Open(path)
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return e, fmt.Errorf("read cached mail %s: %w", path, err)
+	}
+	defer zr.Close()
+	if err := json.NewDecoder(zr).Decode(&e); err != nil {
+		return e, fmt.Errorf("read cached mail %s: %w", path, err)
+	}
+	return e, nil
+}
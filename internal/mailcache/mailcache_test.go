@@ -0,0 +1,162 @@
+package mailcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+func testEmail(id string, date time.Time) types.Email {
+	return types.Email{MessageID: "<" + id + "@example.com>", From: "hr@example.com", Subject: id, Date: date}
+}
+
+// putFetchedAt 保存邮件并把获取时间改为 fetchedAt
+func putFetchedAt(t *testing.T, c *Cache, email types.Email, fetchedAt time.Time) {
+	t.Helper()
+	if _, err := c.Put(email); err != nil {
+		t.Fatal(err)
+	}
+	path := c.path(email.Key())
+	e, err := readEntry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.FetchedAt = fetchedAt
+	if err := writeEntry(path, e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func subjects(t *testing.T, c *Cache) []string {
+	t.Helper()
+	emails, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, e := range emails {
+		out = append(out, e.Subject)
+	}
+	return out
+}
+
+func TestPutGetList(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	email := testEmail("b", now)
+
+	added, err := c.Put(email)
+	if err != nil || !added {
+		t.Fatalf("Put = %v, %v", added, err)
+	}
+	if added, _ := c.Put(email); added {
+		t.Error("second Put reported added")
+	}
+	if _, err := c.Put(testEmail("a", now.Add(-time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.Get(email.Key())
+	if err != nil || got == nil || got.Subject != "b" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	if missing, err := c.Get("nope"); missing != nil || err != nil {
+		t.Errorf("Get missing = %+v, %v", missing, err)
+	}
+	if got := subjects(t, c); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("List = %v, want [a b]", got)
+	}
+}
+
+func TestPruneByFetchTime(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	day := 24 * time.Hour
+
+	// 很早以前的邮件刚刚补抓，不应被清理；很久没有再获取的新邮件按获取时间清理
+	putFetchedAt(t, c, testEmail("old-mail-fresh-fetch", now.Add(-900*day)), now.Add(-time.Hour))
+	putFetchedAt(t, c, testEmail("new-mail-stale-fetch", now.Add(-400*day)), now.Add(-400*day))
+
+	n, err := c.Prune(Retention{MaxAge: 365 * day}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(t, c); n != 1 || len(got) != 1 || got[0] != "old-mail-fresh-fetch" {
+		t.Errorf("Prune removed %d, left %v", n, got)
+	}
+}
+
+func TestPruneKeepsCurrentFetch(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	fetchStart := now.Add(-time.Minute)
+
+	putFetchedAt(t, c, testEmail("earlier-recent", now.Add(-24*time.Hour)), now.Add(-48*time.Hour))
+	putFetchedAt(t, c, testEmail("earlier-newest", now), now.Add(-48*time.Hour))
+	putFetchedAt(t, c, testEmail("backfill-1", now.Add(-800*24*time.Hour)), now)
+	putFetchedAt(t, c, testEmail("backfill-2", now.Add(-700*24*time.Hour)), now)
+
+	// 超出一封：本次获取的旧邮件受保护，删除之前获取的最早的一封
+	n, err := c.Prune(Retention{MaxMessages: 3, KeepFetchedSince: fetchStart}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := subjects(t, c)
+	want := []string{"backfill-1", "backfill-2", "earlier-newest"}
+	if n != 1 || len(got) != len(want) {
+		t.Fatalf("Prune removed %d, left %v, want %v", n, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("left %v, want %v", got, want)
+			break
+		}
+	}
+
+	// 只剩受保护的邮件时即使超出上限也不清理
+	n, err = c.Prune(Retention{MaxMessages: 1, KeepFetchedSince: fetchStart}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(t, c); n != 1 || len(got) != 2 {
+		t.Errorf("Prune removed %d, left %v", n, got)
+	}
+}
+
+func TestCorruptEntrySkipped(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Put(testEmail("good", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := filepath.Join(dir, "00", "broken"+fileExt)
+	if err := os.MkdirAll(filepath.Dir(bad), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("not gzip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := subjects(t, c); len(got) != 1 || got[0] != "good" {
+		t.Errorf("List = %v, want [good]", got)
+	}
+	if _, err := c.Prune(Retention{MaxMessages: 10}, time.Now()); err != nil {
+		t.Errorf("Prune with corrupt file: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// 跟进提醒的状态
+const (
+	FollowUpStale   = "STALE"   // 超过阈值没有新邮件，建议跟进
+	FollowUpGhosted = "GHOSTED" // 长时间没有回音，可能已被搁置
+)
+
+// FollowUpRules 各阶段的等待天数。没有配置的阶段（包括结束状态）不做提醒
+type FollowUpRules struct {
+	StaleDays   map[types.Status]int
+	GhostedDays map[types.Status]int
+}
+
+// DefaultFollowUpRules 默认规则：投递后14天、笔试后7天、面试后7天未收到新邮件时提醒
+func DefaultFollowUpRules() FollowUpRules {
+	return FollowUpRules{
+		StaleDays: map[types.Status]int{
+			types.StatusApplied:   14,
+			types.StatusOA:        7,
+			types.StatusInterview: 7,
+		},
+		GhostedDays: map[types.Status]int{
+			types.StatusApplied:   30,
+			types.StatusOA:        14,
+			types.StatusInterview: 21,
+		},
+	}
+}
+
+// FollowUp 需要跟进的申请
+type FollowUp struct {
+	Application Application
+	State       string
+	// 最近一封相关邮件的日期
+	LastActivity time.Time
+	IdleDays     int
+	// 建议的跟进日期：最近活动 + 阶段阈值，已过去时为今天
+	SuggestedDate time.Time
+}
+
+// FindFollowUps 找出超过阶段阈值没有新邮件的申请，按等待天数从多到少排列
+func FindFollowUps(apps []Application, rules FollowUpRules, now time.Time) []FollowUp {
+	today := truncateDay(now)
+
+	var followUps []FollowUp
+	for _, app := range apps {
+		staleDays, ok := rules.StaleDays[app.Status]
+		if !ok || staleDays <= 0 {
+			continue
+		}
+
+		latest := app.Latest()
+		if latest == nil {
+			continue
+		}
+		lastActivity := latest.Date
+		idleDays := daysBetween(lastActivity, now)
+		if idleDays < staleDays {
+			continue
+		}
+
+		followUp := FollowUp{
+			Application:   app,
+			State:         FollowUpStale,
+			LastActivity:  lastActivity,
+			IdleDays:      idleDays,
+			SuggestedDate: truncateDay(lastActivity).AddDate(0, 0, staleDays),
+		}
+		if ghostedDays, ok := rules.GhostedDays[app.Status]; ok && ghostedDays > 0 && idleDays >= ghostedDays {
+			followUp.State = FollowUpGhosted
+		}
+		if followUp.SuggestedDate.Before(today) {
+			followUp.SuggestedDate = today
+		}
+		followUps = append(followUps, followUp)
+	}
+
+	sort.SliceStable(followUps, func(i, j int) bool { return followUps[i].IdleDays > followUps[j].IdleDays })
+	return followUps
+}
+
+// daysBetween 两个时间按本地日期相差的天数。按日期计算，跨夏令时切换（一天只有23小时）时也不会少算一天
+func daysBetween(from, to time.Time) int {
+	from, to = truncateDay(from), truncateDay(to)
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// followUpApp 只有一封邮件、状态为 status 的申请
+func followUpApp(key string, status types.Status, last time.Time) Application {
+	return Application{
+		Key:    key,
+		Status: status,
+		Emails: []LinkedEmail{{MessageID: key, Status: status, Date: last}},
+	}
+}
+
+func TestFindFollowUps(t *testing.T) {
+	now := time.Date(2025, 3, 20, 15, 0, 0, 0, time.Local)
+	today := time.Date(2025, 3, 20, 0, 0, 0, 0, time.Local)
+	daysAgo := func(days, hour int) time.Time {
+		return time.Date(2025, 3, 20-days, hour, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name      string
+		status    types.Status
+		last      time.Time
+		wantState string // 空表示不提醒
+		wantIdle  int
+	}{
+		{"applied 13 days", types.StatusApplied, daysAgo(13, 8), "", 0},
+		{"applied exactly 14 days", types.StatusApplied, daysAgo(14, 23), FollowUpStale, 14},
+		{"applied 29 days", types.StatusApplied, daysAgo(29, 9), FollowUpStale, 29},
+		{"applied exactly 30 days", types.StatusApplied, daysAgo(30, 9), FollowUpGhosted, 30},
+		{"oa 6 days", types.StatusOA, daysAgo(6, 0), "", 0},
+		{"oa exactly 7 days", types.StatusOA, daysAgo(7, 0), FollowUpStale, 7},
+		{"oa exactly 14 days", types.StatusOA, daysAgo(14, 12), FollowUpGhosted, 14},
+		{"interview 20 days", types.StatusInterview, daysAgo(20, 10), FollowUpStale, 20},
+		{"interview exactly 21 days", types.StatusInterview, daysAgo(21, 10), FollowUpGhosted, 21},
+		{"later today", types.StatusInterview, now.Add(2 * time.Hour), "", 0},
+		{"offer", types.StatusOffer, daysAgo(100, 9), "", 0},
+		{"rejected", types.StatusRejected, daysAgo(100, 9), "", 0},
+		{"withdrawn", types.StatusWithdrawn, daysAgo(100, 9), "", 0},
+		{"other", types.StatusOther, daysAgo(100, 9), "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindFollowUps([]Application{followUpApp("acme|engineer", tt.status, tt.last)}, DefaultFollowUpRules(), now)
+			if tt.wantState == "" {
+				if len(got) != 0 {
+					t.Fatalf("got follow-up %+v, want none", got[0])
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d follow-ups, want 1", len(got))
+			}
+			f := got[0]
+			if f.State != tt.wantState || f.IdleDays != tt.wantIdle {
+				t.Errorf("state %s, idle %d; want %s, %d", f.State, f.IdleDays, tt.wantState, tt.wantIdle)
+			}
+			if !f.LastActivity.Equal(tt.last) {
+				t.Errorf("last activity = %v, want %v", f.LastActivity, tt.last)
+			}
+			// 到期当天或已过期时建议今天跟进
+			if !f.SuggestedDate.Equal(today) {
+				t.Errorf("suggested date = %v, want %v", f.SuggestedDate, today)
+			}
+		})
+	}
+}
+
+func TestFindFollowUpsOrderAndRules(t *testing.T) {
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.Local)
+	apps := []Application{
+		followUpApp("a|applied", types.StatusApplied, now.AddDate(0, 0, -15)),
+		followUpApp("b|interview", types.StatusInterview, now.AddDate(0, 0, -40)),
+		followUpApp("c|oa", types.StatusOA, now.AddDate(0, 0, -8)),
+		{Key: "d|empty", Status: types.StatusApplied}, // 没有邮件
+	}
+
+	got := FindFollowUps(apps, DefaultFollowUpRules(), now)
+	var keys []string
+	for _, f := range got {
+		keys = append(keys, f.Application.Key)
+	}
+	if want := []string{"b|interview", "a|applied", "c|oa"}; !equalStrings(keys, want) {
+		t.Errorf("follow-ups = %v, want %v (most idle first)", keys, want)
+	}
+
+	// 阈值为0或没有配置的阶段不提醒
+	rules := FollowUpRules{StaleDays: map[types.Status]int{types.StatusApplied: 0, types.StatusOA: 7}}
+	got = FindFollowUps(apps, rules, now)
+	if len(got) != 1 || got[0].Application.Key != "c|oa" || got[0].State != FollowUpStale {
+		t.Errorf("custom rules follow-ups = %+v, want only c|oa as STALE", got)
+	}
+}
+
+func TestFindFollowUpsAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+	saved := time.Local
+	time.Local = loc
+	defer func() { time.Local = saved }()
+
+	// 2025-03-09 开始夏令时，这14个日历日只有 14*24-1 小时
+	last := time.Date(2025, 3, 2, 10, 0, 0, 0, loc)
+	now := time.Date(2025, 3, 16, 9, 0, 0, 0, loc)
+	got := FindFollowUps([]Application{followUpApp("acme|engineer", types.StatusApplied, last)}, DefaultFollowUpRules(), now)
+	if len(got) != 1 || got[0].IdleDays != 14 {
+		t.Fatalf("follow-ups = %+v, want one with 14 idle days", got)
+	}
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// 公司名称中不影响识别的后缀（按顺序逐个去掉）。
+// 中文后缀直接去掉；英文后缀必须是空格分隔的完整单词，避免 "Cisco" 变成 "cis"
+var (
+	cjkCompanySuffixes = []string{"有限责任公司", "股份有限公司", "有限公司", "集团", "公司"}
+	companySuffixes    = []string{
+		"incorporated", "corporation", "company", "limited",
+		"inc", "corp", "co", "ltd", "llc", "gmbh", "plc",
+	}
+)
+
+var nonWord = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// NormalizeCompany 统一公司名称：小写、去掉标点和常见后缀
+func NormalizeCompany(company string) string {
+	name := strings.TrimSpace(nonWord.ReplaceAllString(strings.ToLower(company), " "))
+	for changed := true; changed; {
+		changed = false
+		for _, suffix := range cjkCompanySuffixes {
+			trimmed := strings.TrimSpace(strings.TrimSuffix(name, suffix))
+			if trimmed != name && trimmed != "" {
+				name, changed = trimmed, true
+			}
+		}
+		for _, suffix := range companySuffixes {
+			if trimmed, ok := strings.CutSuffix(name, " "+suffix); ok && trimmed != "" {
+				name, changed = strings.TrimSpace(trimmed), true
+			}
+		}
+	}
+	return strings.ReplaceAll(name, " ", "")
+}
+
+// NormalizePosition 统一职位名称：小写并合并空白和标点
+func NormalizePosition(position string) string {
+	return strings.TrimSpace(nonWord.ReplaceAllString(strings.ToLower(position), " "))
+}
+
+// ApplicationKey 申请的唯一键：规范化公司名 + 职位编号（优先）或规范化职位名
+func ApplicationKey(company, position, requisitionID string) string {
+	key := NormalizeCompany(company) + "|"
+	if id := strings.TrimSpace(requisitionID); id != "" {
+		return key + "req:" + strings.ToLower(id)
+	}
+	return key + NormalizePosition(position)
+}
+
+// emailKey 邮件的去重键，见 types.Email.Key
+func emailKey(email types.Email) string {
+	return email.Key()
+}
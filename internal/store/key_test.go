@@ -0,0 +1,38 @@
+package store
+
+import "testing"
+
+func TestNormalizeCompany(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Cisco", "cisco"},
+		{"Costco", "costco"},
+		{"Tesco", "tesco"},
+		{"Telco Inc.", "telco"},
+		{"Cisco Systems, Inc.", "ciscosystems"},
+		{"Acme Co., Ltd.", "acme"},
+		{"ByteDance Ltd", "bytedance"},
+		{"Siemens GmbH", "siemens"},
+		{"Inc", "inc"},
+		{"腾讯科技（深圳）有限公司", "腾讯科技深圳"},
+		{"阿里巴巴集团", "阿里巴巴"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeCompany(tt.in); got != tt.want {
+			t.Errorf("NormalizeCompany(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestApplicationKey(t *testing.T) {
+	if a, b := ApplicationKey("Tesco PLC", "Engineer", ""), ApplicationKey("tesco", "engineer", ""); a != b {
+		t.Errorf("keys differ: %q vs %q", a, b)
+	}
+	if a, b := ApplicationKey("Tesco", "Engineer", ""), ApplicationKey("Tes", "Engineer", ""); a == b {
+		t.Errorf("Tesco and Tes share key %q", a)
+	}
+	if got, want := ApplicationKey("Acme Inc", "Engineer", " R-123 "), "acme|req:r-123"; got != want {
+		t.Errorf("ApplicationKey with requisition = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,343 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+var (
+	applicationsBucket = []byte("applications") // 申请键 -> Application JSON
+	messagesBucket     = []byte("messages")     // 邮件去重键 -> 申请键
+	analyzedBucket     = []byte("analyzed")     // 邮件去重键 -> 分析时间（包括不相关的邮件）
+)
+
+// Application 一个求职申请，由同一公司同一职位的多封邮件合并而来
+type Application struct {
+	Key           string `json:"key"`
+	Company       string `json:"company"`
+	Position      string `json:"position"`
+	RequisitionID string `json:"requisition_id,omitempty"`
+	Location      string `json:"location,omitempty"`
+	// 当前状态，由时间线决定
+	Status types.Status `json:"status"`
+	// 关联的邮件，按邮件日期从早到晚排列
+	Emails []LinkedEmail `json:"emails"`
+	// 按邮件重放得到的状态变化
+	Timeline  []Transition `json:"timeline"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// LinkedEmail 关联到申请的一封邮件及其分析结果
+type LinkedEmail struct {
+	MessageID   string       `json:"message_id"`
+	From        string       `json:"from"`
+	Subject     string       `json:"subject"`
+	Date        time.Time    `json:"date"`
+	Folder      string       `json:"folder,omitempty"`
+	Status      types.Status `json:"status"`
+	Description string       `json:"description,omitempty"`
+	// 笔试/面试安排，EventUID 为日历邀请的UID（改期邀请沿用同一UID）
+	EventStart time.Time `json:"event_start,omitempty"`
+	EventEnd   time.Time `json:"event_end,omitempty"`
+	Deadline   time.Time `json:"deadline,omitempty"`
+	Timezone   string    `json:"timezone,omitempty"`
+	MeetingURL string    `json:"meeting_url,omitempty"`
+	EventUID   string    `json:"event_uid,omitempty"`
+}
+
+// Latest 最近的一封邮件，没有邮件时返回 nil
+func (a *Application) Latest() *LinkedEmail {
+	if len(a.Emails) == 0 {
+		return nil
+	}
+	return &a.Emails[len(a.Emails)-1]
+}
+
+// Store 基于bbolt的申请存储
+type Store struct {
+	db *bolt.DB
+}
+
+// Open 打开（必要时创建）数据库文件
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create store dir: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{applicationsBucket, messagesBucket, analyzedBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init store: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record 记录一封邮件的分析结果，替换这封邮件之前的结果（用于修改提示词后重新分析）。
+// jobApp 为 nil 表示不是求职邮件，之前关联的申请会移除这封邮件，没有邮件的申请会被删除
+func (s *Store) Record(email types.Email, jobApp *types.JobApplication) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := s.unlink(tx, emailKey(email)); err != nil {
+			return err
+		}
+		if jobApp != nil {
+			if err := s.add(tx, *jobApp); err != nil {
+				return err
+			}
+		}
+		return markAnalyzed(tx, email)
+	})
+}
+
+// Analyzed 邮件是否已经分析过（无论是否求职相关）
+func (s *Store) Analyzed(email types.Email) (bool, error) {
+	var analyzed bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		analyzed = tx.Bucket(analyzedBucket).Get([]byte(emailKey(email))) != nil
+		return nil
+	})
+	return analyzed, err
+}
+
+func markAnalyzed(tx *bolt.Tx, email types.Email) error {
+	return tx.Bucket(analyzedBucket).Put([]byte(emailKey(email)), []byte(time.Now().Format(time.RFC3339)))
+}
+
+// add 把一封已分析的邮件合并到对应的申请中，已关联过的邮件（按去重键）不做任何修改
+func (s *Store) add(tx *bolt.Tx, jobApp types.JobApplication) error {
+	apps := tx.Bucket(applicationsBucket)
+	messages := tx.Bucket(messagesBucket)
+
+	msgKey := []byte(emailKey(jobApp.Email))
+	if messages.Get(msgKey) != nil {
+		return nil
+	}
+
+	key, err := s.resolveKey(apps, jobApp)
+	if err != nil {
+		return err
+	}
+
+	var app *Application
+	if data := apps.Get([]byte(key)); data != nil {
+		if app, err = decodeApplication(data); err != nil {
+			return err
+		}
+	} else {
+		app = &Application{
+			Key:           key,
+			Company:       jobApp.Company,
+			Position:      jobApp.Position,
+			RequisitionID: jobApp.RequisitionID,
+			CreatedAt:     time.Now(),
+		}
+	}
+
+	app.link(jobApp)
+
+	if err := putApplication(apps, app); err != nil {
+		return err
+	}
+	return messages.Put(msgKey, []byte(key))
+}
+
+// unlink 从所属申请中移除一封邮件，邮件未关联任何申请时不做任何事
+func (s *Store) unlink(tx *bolt.Tx, msgKey string) error {
+	apps := tx.Bucket(applicationsBucket)
+	messages := tx.Bucket(messagesBucket)
+
+	appKey := messages.Get([]byte(msgKey))
+	if appKey == nil {
+		return nil
+	}
+	appKey = append([]byte(nil), appKey...) // Delete 之后原切片不再有效
+	if err := messages.Delete([]byte(msgKey)); err != nil {
+		return err
+	}
+
+	data := apps.Get(appKey)
+	if data == nil {
+		return nil
+	}
+	app, err := decodeApplication(data)
+	if err != nil {
+		return err
+	}
+
+	kept := app.Emails[:0]
+	for _, e := range app.Emails {
+		if e.MessageID != msgKey {
+			kept = append(kept, e)
+		}
+	}
+	app.Emails = kept
+	if len(app.Emails) == 0 {
+		return apps.Delete(appKey)
+	}
+
+	app.Status, app.Timeline = buildTimeline(app.Emails)
+	app.UpdatedAt = time.Now()
+	return putApplication(apps, app)
+}
+
+func putApplication(apps *bolt.Bucket, app *Application) error {
+	data, err := json.Marshal(app)
+	if err != nil {
+		return fmt.Errorf("marshal application: %w", err)
+	}
+	return apps.Put([]byte(app.Key), data)
+}
+
+// resolveKey 计算邮件所属申请的键。键不存在时在同一公司的申请中查找：
+//   - 职位编号相同（之前的邮件没有编号、后来补上的申请）；
+//   - 职位名相同且编号不冲突，例如确认邮件带编号、后来的面试邮件没有编号；
+//   - 邮件没有职位信息（如拒信只提公司）时，该公司唯一的申请。
+//
+// 候选不唯一时无法确定，使用邮件自己的键
+func (s *Store) resolveKey(apps *bolt.Bucket, jobApp types.JobApplication) (string, error) {
+	key := ApplicationKey(jobApp.Company, jobApp.Position, jobApp.RequisitionID)
+	if apps.Get([]byte(key)) != nil {
+		return key, nil
+	}
+
+	reqID := strings.TrimSpace(jobApp.RequisitionID)
+	position := NormalizePosition(jobApp.Position)
+	if reqID != "" && position == "" {
+		return key, nil
+	}
+
+	var matches []string
+	prefix := []byte(NormalizeCompany(jobApp.Company) + "|")
+	c := apps.Cursor()
+	for k, data := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, data = c.Next() {
+		app, err := decodeApplication(data)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case reqID != "" && strings.EqualFold(app.RequisitionID, reqID):
+			return app.Key, nil
+		case position == "" && reqID == "":
+			matches = append(matches, app.Key)
+		case NormalizePosition(app.Position) != position:
+		case reqID != "" && app.RequisitionID != "":
+			// 职位名相同但编号不同，是不同的申请
+		default:
+			matches = append(matches, app.Key)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return key, nil
+}
+
+// link 添加邮件并按日期重新排序，重新计算当前状态和时间线
+func (a *Application) link(jobApp types.JobApplication) {
+	a.Emails = append(a.Emails, LinkedEmail{
+		MessageID:   emailKey(jobApp.Email),
+		From:        jobApp.Email.From,
+		Subject:     jobApp.Email.Subject,
+		Date:        jobApp.Email.Date,
+		Folder:      jobApp.Email.Folder,
+		Status:      jobApp.Status,
+		Description: jobApp.Description,
+		EventStart:  jobApp.EventStart,
+		EventEnd:    jobApp.EventEnd,
+		Deadline:    jobApp.Deadline,
+		Timezone:    jobApp.Timezone,
+		MeetingURL:  jobApp.MeetingURL,
+		EventUID:    inviteUID(jobApp.Email.Invites),
+	})
+	sort.SliceStable(a.Emails, func(i, j int) bool { return a.Emails[i].Date.Before(a.Emails[j].Date) })
+
+	// 补全之前邮件中缺失的信息
+	if a.Position == "" {
+		a.Position = jobApp.Position
+	}
+	if a.Location == "" {
+		a.Location = jobApp.Location
+	}
+	if a.RequisitionID == "" {
+		a.RequisitionID = strings.TrimSpace(jobApp.RequisitionID)
+	}
+	a.Status, a.Timeline = buildTimeline(a.Emails)
+	a.UpdatedAt = time.Now()
+}
+
+// List 返回所有申请，按最近邮件日期从新到旧排列
+func (s *Store) List() ([]Application, error) {
+	var apps []Application
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(applicationsBucket).ForEach(func(_, data []byte) error {
+			app, err := decodeApplication(data)
+			if err != nil {
+				return err
+			}
+			apps = append(apps, *app)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(apps, func(i, j int) bool {
+		return lastDate(apps[i]).After(lastDate(apps[j]))
+	})
+	return apps, nil
+}
+
+func lastDate(app Application) time.Time {
+	if latest := app.Latest(); latest != nil {
+		return latest.Date
+	}
+	return app.UpdatedAt
+}
+
+func decodeApplication(data []byte) (*Application, error) {
+	var app Application
+	if err := json.Unmarshal(data, &app); err != nil {
+		return nil, fmt.Errorf("decode application: %w", err)
+	}
+	// 读取时按当前规则重放，规则调整后旧数据也随之更新
+	app.Status, app.Timeline = buildTimeline(app.Emails)
+	return &app, nil
+}
+
+// inviteUID 邮件中第一个日历邀请的UID
+func inviteUID(invites []types.CalendarInvite) string {
+	for _, inv := range invites {
+		if inv.UID != "" {
+			return inv.UID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "applications.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+var day0 = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func testEmail(id string, day int) types.Email {
+	return types.Email{
+		MessageID: "<" + id + "@example.com>",
+		From:      "recruiting@example.com",
+		Subject:   "Update " + id,
+		Date:      day0.AddDate(0, 0, day),
+	}
+}
+
+func testApp(email types.Email, company, position, reqID string, status types.Status) *types.JobApplication {
+	return &types.JobApplication{
+		Company:       company,
+		Position:      position,
+		RequisitionID: reqID,
+		Status:        status,
+		Email:         email,
+	}
+}
+
+func mustRecord(t *testing.T, s *Store, email types.Email, app *types.JobApplication) {
+	t.Helper()
+	if err := s.Record(email, app); err != nil {
+		t.Fatalf("Record(%s): %v", email.MessageID, err)
+	}
+}
+
+func mustList(t *testing.T, s *Store) []Application {
+	t.Helper()
+	apps, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return apps
+}
+
+// linked 申请关联的邮件去重键，按日期排列
+func linked(app Application) []string {
+	var ids []string
+	for _, e := range app.Emails {
+		ids = append(ids, e.MessageID)
+	}
+	return ids
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRecordSameEmailTwice(t *testing.T) {
+	s := openTestStore(t)
+	email := testEmail("a1", 0)
+
+	if analyzed, _ := s.Analyzed(email); analyzed {
+		t.Fatal("email analyzed before Record")
+	}
+	for i := 0; i < 2; i++ {
+		mustRecord(t, s, email, testApp(email, "Acme Inc.", "Backend Engineer", "", types.StatusApplied))
+	}
+	if analyzed, err := s.Analyzed(email); err != nil || !analyzed {
+		t.Errorf("Analyzed = %v, %v; want true", analyzed, err)
+	}
+
+	apps := mustList(t, s)
+	if len(apps) != 1 {
+		t.Fatalf("got %d applications, want 1", len(apps))
+	}
+	if got := linked(apps[0]); !equalStrings(got, []string{"a1@example.com"}) {
+		t.Errorf("linked emails = %v, want the email once", got)
+	}
+	if apps[0].Key != "acme|backend engineer" || apps[0].Status != types.StatusApplied {
+		t.Errorf("application = %s %s", apps[0].Key, apps[0].Status)
+	}
+}
+
+func TestRecordReclassifiedEmail(t *testing.T) {
+	s := openTestStore(t)
+	applied := testEmail("a1", 0)
+	interview := testEmail("a2", 3)
+
+	mustRecord(t, s, applied, testApp(applied, "Acme", "Backend Engineer", "", types.StatusApplied))
+	mustRecord(t, s, interview, testApp(interview, "Acme", "Backend Engineer", "", types.StatusInterview))
+	apps := mustList(t, s)
+	if len(apps) != 1 || apps[0].Status != types.StatusInterview {
+		t.Fatalf("before reanalysis: %d applications, status %v", len(apps), apps[0].Status)
+	}
+
+	// 重新分析后第二封邮件属于另一家公司：从原申请移除，原申请的状态随之回退
+	mustRecord(t, s, interview, testApp(interview, "Globex", "Data Analyst", "", types.StatusInterview))
+	apps = mustList(t, s)
+	if len(apps) != 2 {
+		t.Fatalf("after reclassifying: got %d applications, want 2", len(apps))
+	}
+	byKey := map[string]Application{}
+	for _, app := range apps {
+		byKey[app.Key] = app
+	}
+	acme, globex := byKey["acme|backend engineer"], byKey["globex|data analyst"]
+	if got := linked(acme); !equalStrings(got, []string{"a1@example.com"}) || acme.Status != types.StatusApplied {
+		t.Errorf("acme = %v %s, want only a1 and APPLIED", got, acme.Status)
+	}
+	if got := linked(globex); !equalStrings(got, []string{"a2@example.com"}) {
+		t.Errorf("globex = %v, want only a2", got)
+	}
+
+	// 不再是求职邮件：没有邮件的申请被删除，邮件仍记为已分析
+	mustRecord(t, s, applied, nil)
+	apps = mustList(t, s)
+	if len(apps) != 1 || apps[0].Key != "globex|data analyst" {
+		t.Fatalf("after marking unrelated: %v", apps)
+	}
+	if analyzed, _ := s.Analyzed(applied); !analyzed {
+		t.Error("unrelated email not marked analyzed")
+	}
+}
+
+func TestRecordMergesRequisitionID(t *testing.T) {
+	tests := []struct {
+		name   string
+		emails []*types.JobApplication
+		want   int
+	}{
+		{
+			name: "req ID first",
+			emails: []*types.JobApplication{
+				testApp(testEmail("r1", 0), "Acme", "Backend Engineer", "R-1024", types.StatusApplied),
+				testApp(testEmail("r2", 5), "Acme Inc.", "backend engineer", "", types.StatusInterview),
+			},
+			want: 1,
+		},
+		{
+			name: "req ID on a differently titled email",
+			emails: []*types.JobApplication{
+				testApp(testEmail("r1", 0), "Acme", "Backend Engineer", "", types.StatusApplied),
+				testApp(testEmail("r2", 5), "Acme", "Backend Engineer (Remote)", "R-1024", types.StatusInterview),
+				testApp(testEmail("r3", 8), "Acme", "Backend Engineer", "r-1024", types.StatusOffer),
+			},
+			want: 2, // 职位名不同的带编号邮件单独记录，编号相同的后续邮件归入它
+		},
+		{
+			name: "same req ID, renamed position",
+			emails: []*types.JobApplication{
+				testApp(testEmail("r1", 0), "Acme", "Backend Engineer", "", types.StatusApplied),
+				testApp(testEmail("r2", 5), "Acme", "Backend Engineer", "R-1024", types.StatusInterview),
+				testApp(testEmail("r3", 8), "Acme", "Software Engineer, Backend", "R-1024", types.StatusOffer),
+			},
+			want: 1,
+		},
+		{
+			name: "different req IDs with the same title",
+			emails: []*types.JobApplication{
+				testApp(testEmail("r1", 0), "Acme", "Backend Engineer", "R-1", types.StatusApplied),
+				testApp(testEmail("r2", 1), "Acme", "Backend Engineer", "R-2", types.StatusApplied),
+			},
+			want: 2,
+		},
+		{
+			name: "company-only rejection",
+			emails: []*types.JobApplication{
+				testApp(testEmail("r1", 0), "Acme", "Backend Engineer", "R-1", types.StatusApplied),
+				testApp(testEmail("r2", 9), "ACME Inc", "", "", types.StatusRejected),
+			},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openTestStore(t)
+			for _, app := range tt.emails {
+				mustRecord(t, s, app.Email, app)
+			}
+			apps := mustList(t, s)
+			if len(apps) != tt.want {
+				for _, app := range apps {
+					t.Logf("%s: %v", app.Key, linked(app))
+				}
+				t.Fatalf("got %d applications, want %d", len(apps), tt.want)
+			}
+			if tt.want == 1 {
+				last := tt.emails[len(tt.emails)-1]
+				if apps[0].Status != last.Status || len(apps[0].Emails) != len(tt.emails) {
+					t.Errorf("application %s: status %s with %d emails", apps[0].Key, apps[0].Status, len(apps[0].Emails))
+				}
+				if apps[0].RequisitionID == "" {
+					t.Errorf("application %s lost its requisition ID", apps[0].Key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+// Transition 申请状态的一次变化及触发它的邮件
+type Transition struct {
+	From      types.Status `json:"from,omitempty"` // 第一步为空
+	To        types.Status `json:"to"`
+	At        time.Time    `json:"at"`
+	MessageID string       `json:"message_id"`
+	Subject   string       `json:"subject"`
+	// 第几轮面试（To 为 INTERVIEW 时）
+	Round int `json:"round,omitempty"`
+	// 不合理的状态变化（如 OFFER 之后收到 APPLIED 自动回复）不会改变当前状态，只做标记
+	Invalid bool   `json:"invalid,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
+
+// buildTimeline 按邮件日期重放状态变化，返回当前状态和时间线。
+// 每次都从头重放，邮件到达顺序不影响结果
+func buildTimeline(emails []LinkedEmail) (types.Status, []Transition) {
+	var (
+		current  types.Status
+		rounds   int
+		timeline []Transition
+	)
+
+	for _, email := range emails {
+		to := email.Status
+		if to.Stage() == 0 {
+			continue // OTHER 不参与状态流转
+		}
+
+		t := Transition{
+			From:      current,
+			To:        to,
+			At:        email.Date,
+			MessageID: email.MessageID,
+			Subject:   email.Subject,
+		}
+
+		switch {
+		case current == "":
+		case to == current && !to.Repeatable():
+			continue // 重复通知，如多封投递确认
+		case !types.CanTransition(current, to):
+			t.Invalid = true
+			t.Note = fmt.Sprintf("%s 之后不应出现 %s", current, to)
+			timeline = append(timeline, t)
+			continue
+		}
+
+		if to == types.StatusInterview {
+			rounds++
+			t.Round = rounds
+		}
+		current = to
+		timeline = append(timeline, t)
+	}
+
+	if current == "" {
+		current = types.StatusOther
+	}
+	return current, timeline
+}
+
+// Rounds 面试轮数
+func (a *Application) Rounds() int {
+	rounds := 0
+	for _, t := range a.Timeline {
+		if !t.Invalid && t.Round > rounds {
+			rounds = t.Round
+		}
+	}
+	return rounds
+}
+
+// Flagged 被标记为不合理的状态变化
+func (a *Application) Flagged() []Transition {
+	var flagged []Transition
+	for _, t := range a.Timeline {
+		if t.Invalid {
+			flagged = append(flagged, t)
+		}
+	}
+	return flagged
+}
+
+// TimelineString 紧凑的时间线，如 "APPLIED(01-02) → OA(01-05) → INTERVIEW#1(01-10)"，
+// 不合理的变化用 "!" 标记
+func (a *Application) TimelineString() string {
+	var steps []string
+	for _, t := range a.Timeline {
+		step := string(t.To)
+		if t.Round > 0 {
+			step += fmt.Sprintf("#%d", t.Round)
+		}
+		step += "(" + t.At.Format("01-02") + ")"
+		if t.Invalid {
+			step = "!" + step
+		}
+		steps = append(steps, step)
+	}
+	return strings.Join(steps, " → ")
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/YKarmar/JobTracker/internal/types"
+)
+
+func TestTimeline(t *testing.T) {
+	type step struct {
+		status types.Status
+		day    int
+	}
+	tests := []struct {
+		name string
+		// 按到达顺序排列，日期可能乱序
+		emails       []step
+		wantStatus   types.Status
+		wantTimeline string
+		wantRounds   int
+		wantFlagged  int
+	}{
+		{
+			name:         "out of order arrival",
+			emails:       []step{{types.StatusInterview, 5}, {types.StatusApplied, 0}, {types.StatusOA, 2}},
+			wantStatus:   types.StatusInterview,
+			wantTimeline: "APPLIED(03-01) → OA(03-03) → INTERVIEW#1(03-06)",
+			wantRounds:   1,
+		},
+		{
+			name: "repeated interview",
+			emails: []step{
+				{types.StatusApplied, 0}, {types.StatusApplied, 1}, {types.StatusInterview, 3},
+				{types.StatusOffer, 10}, {types.StatusInterview, 7},
+			},
+			wantStatus:   types.StatusOffer,
+			wantTimeline: "APPLIED(03-01) → INTERVIEW#1(03-04) → INTERVIEW#2(03-08) → OFFER(03-11)",
+			wantRounds:   2,
+		},
+		{
+			name:         "interview after rejection",
+			emails:       []step{{types.StatusApplied, 0}, {types.StatusRejected, 3}, {types.StatusInterview, 5}},
+			wantStatus:   types.StatusRejected,
+			wantTimeline: "APPLIED(03-01) → REJECTED(03-04) → !INTERVIEW(03-06)",
+			wantRounds:   0,
+			wantFlagged:  1,
+		},
+		{
+			name:         "applied after interview",
+			emails:       []step{{types.StatusInterview, 0}, {types.StatusApplied, 1}, {types.StatusInterview, 4}},
+			wantStatus:   types.StatusInterview,
+			wantTimeline: "INTERVIEW#1(03-01) → !APPLIED(03-02) → INTERVIEW#2(03-05)",
+			wantRounds:   2,
+			wantFlagged:  1,
+		},
+		{
+			name:         "offer rescinded",
+			emails:       []step{{types.StatusOffer, 0}, {types.StatusOther, 1}, {types.StatusRejected, 2}},
+			wantStatus:   types.StatusRejected,
+			wantTimeline: "OFFER(03-01) → REJECTED(03-03)",
+		},
+		{
+			name:         "only unrelated updates",
+			emails:       []step{{types.StatusOther, 0}},
+			wantStatus:   types.StatusOther,
+			wantTimeline: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &Application{}
+			for i, s := range tt.emails {
+				email := testEmail(fmt.Sprintf("m%d", i), s.day)
+				app.link(*testApp(email, "Acme", "Backend Engineer", "", s.status))
+			}
+			if app.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", app.Status, tt.wantStatus)
+			}
+			if got := app.TimelineString(); got != tt.wantTimeline {
+				t.Errorf("timeline = %q, want %q", got, tt.wantTimeline)
+			}
+			if got := app.Rounds(); got != tt.wantRounds {
+				t.Errorf("rounds = %d, want %d", got, tt.wantRounds)
+			}
+			flagged := app.Flagged()
+			if len(flagged) != tt.wantFlagged {
+				t.Errorf("flagged = %+v, want %d", flagged, tt.wantFlagged)
+			}
+			for _, f := range flagged {
+				if f.Note == "" || f.From == "" {
+					t.Errorf("flagged transition without context: %+v", f)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package types
+
+// 求职流程中的阶段：APPLIED → OA → INTERVIEW（可多轮）→ OFFER/REJECTED/WITHDRAWN
+var statusStage = map[Status]int{
+	StatusApplied:   1,
+	StatusOA:        2,
+	StatusInterview: 3,
+	StatusOffer:     4,
+	StatusRejected:  4,
+	StatusWithdrawn: 4,
+}
+
+// Stage 状态所处的阶段，OTHER 和未知状态为 0（不参与流程）
+func (s Status) Stage() int {
+	return statusStage[s]
+}
+
+// IsTerminal 是否为结束状态
+func (s Status) IsTerminal() bool {
+	return s == StatusOffer || s == StatusRejected || s == StatusWithdrawn
+}
+
+// Repeatable 同一状态能否重复出现并记为新的一步（多轮笔试、多轮面试）
+func (s Status) Repeatable() bool {
+	return s == StatusOA || s == StatusInterview
+}
+
+// CanTransition 判断从 from 到 to 是否是合理的推进。
+// 允许跳过阶段（如直接收到面试邀请），不允许回退到更早的阶段；
+// 结束状态之后只允许 OFFER 被撤回/拒绝（REJECTED）或候选人放弃（WITHDRAWN）
+func CanTransition(from, to Status) bool {
+	if from.Stage() == 0 || to.Stage() == 0 {
+		return false
+	}
+	if from.IsTerminal() {
+		return from == StatusOffer && (to == StatusRejected || to == StatusWithdrawn)
+	}
+	if from == to {
+		return from.Repeatable()
+	}
+	return to.Stage() > from.Stage() || to.IsTerminal()
+}
@@ -0,0 +1,36 @@
+package types
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		want     bool
+	}{
+		{StatusApplied, StatusOA, true},
+		{StatusApplied, StatusInterview, true}, // 跳过笔试
+		{StatusApplied, StatusRejected, true},
+		{StatusOA, StatusOA, true},
+		{StatusOA, StatusApplied, false},
+		{StatusInterview, StatusInterview, true},
+		{StatusInterview, StatusOA, false},
+		{StatusInterview, StatusOffer, true},
+		{StatusInterview, StatusWithdrawn, true},
+		{StatusApplied, StatusApplied, false},
+		{StatusRejected, StatusInterview, false},
+		{StatusRejected, StatusRejected, false},
+		{StatusWithdrawn, StatusOffer, false},
+		{StatusOffer, StatusRejected, true}, // offer 被撤回
+		{StatusOffer, StatusWithdrawn, true},
+		{StatusOffer, StatusInterview, false},
+		{StatusOffer, StatusApplied, false},
+		{StatusOther, StatusApplied, false},
+		{StatusApplied, StatusOther, false},
+		{Status("UNKNOWN"), StatusOffer, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
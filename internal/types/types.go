@@ -1,6 +1,11 @@
 package types
 
-import "time"
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"time"
+)
 
 type Status string
 
@@ -15,22 +20,54 @@ const (
 )
 
 type Email struct {
-	ID        string
-	From      string
-	Subject   string
-	Date      time.Time
-	BodyText  string
-	BodyHTML  string
-	MessageID string
-	Folder    string
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	Subject   string    `json:"subject"`
+	Date      time.Time `json:"date"`
+	BodyText  string    `json:"body_text"`
+	BodyHTML  string    `json:"body_html"`
+	MessageID string    `json:"message_id"`
+	Folder    string    `json:"folder"`
+	// 邮件 text/calendar 部分解析出的日历邀请
+	Invites []CalendarInvite `json:"invites,omitempty"`
+}
+
+// Key 邮件的去重键，优先使用 Message-ID，没有时用发件人、日期和主题
+func (e Email) Key() string {
+	if id := strings.Trim(strings.TrimSpace(e.MessageID), "<>"); id != "" {
+		return id
+	}
+	h := sha1.Sum([]byte(e.From + "\n" + e.Date.UTC().String() + "\n" + e.Subject))
+	return "sha1:" + hex.EncodeToString(h[:])
+}
+
+// CalendarInvite 邮件附带的日历事件（如 METHOD:REQUEST 面试邀请），由MCP服务器解析
+type CalendarInvite struct {
+	Method     string    `json:"method,omitempty"`
+	UID        string    `json:"uid,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end,omitempty"`
+	Timezone   string    `json:"timezone,omitempty"`
+	Location   string    `json:"location,omitempty"`
+	MeetingURL string    `json:"meeting_url,omitempty"`
+	Organizer  string    `json:"organizer,omitempty"`
 }
 
 type JobApplication struct {
-	Company     string    `json:"company"`
-	Position    string    `json:"position"`
-	Status      Status    `json:"status"`
-	Location    string    `json:"location,omitempty"`
-	Description string    `json:"description,omitempty"`
+	Company  string `json:"company"`
+	Position string `json:"position"`
+	// 职位编号（招聘系统中的 requisition ID），邮件中没有时为空
+	RequisitionID string `json:"requisition_id,omitempty"`
+	Status        Status `json:"status"`
+	Location      string `json:"location,omitempty"`
+	Description   string `json:"description,omitempty"`
+	// 笔试/面试时间、截止时间和会议链接，邮件中没有时为零值
+	EventStart  time.Time `json:"event_start,omitempty"`
+	EventEnd    time.Time `json:"event_end,omitempty"`
+	Deadline    time.Time `json:"deadline,omitempty"`
+	Timezone    string    `json:"timezone,omitempty"`
+	MeetingURL  string    `json:"meeting_url,omitempty"`
 	Email       Email     `json:"email"`
 	ExtractedAt time.Time `json:"extracted_at"`
 }